@@ -0,0 +1,122 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Account struct {
+	ID                       string    `json:"id"`
+	AccountNumber            string    `json:"account_number"`
+	Status                   string    `json:"status"`
+	Currency                 string    `json:"currency"`
+	Cash                     Decimal   `json:"cash"`
+	PortfolioValue           Decimal   `json:"portfolio_value"`
+	Equity                   Decimal   `json:"equity"`
+	LastEquity               Decimal   `json:"last_equity"`
+	LongMarketValue          Decimal   `json:"long_market_value"`
+	ShortMarketValue         Decimal   `json:"short_market_value"`
+	BuyingPower              Decimal   `json:"buying_power"`
+	RegTBuyingPower          Decimal   `json:"regt_buying_power"`
+	DaytradingBuyingPower    Decimal   `json:"daytrading_buying_power"`
+	NonMarginableBuyingPower Decimal   `json:"non_marginable_buying_power"`
+	OptionsBuyingPower       Decimal   `json:"options_buying_power"`
+	OptionsApprovedLevel     int       `json:"options_approved_level"`
+	OptionsTradingLevel      int       `json:"options_trading_level"`
+	Multiplier               Decimal   `json:"multiplier"`
+	InitialMargin            Decimal   `json:"initial_margin"`
+	MaintenanceMargin        Decimal   `json:"maintenance_margin"`
+	LastMaintenanceMargin    Decimal   `json:"last_maintenance_margin"`
+	SMA                      Decimal   `json:"sma"`
+	AccruedFees              Decimal   `json:"accrued_fees"`
+	PendingTransferIn        Decimal   `json:"pending_transfer_in"`
+	PendingTransferOut       Decimal   `json:"pending_transfer_out"`
+	PatternDayTrader         bool      `json:"pattern_day_trader"`
+	DaytradeCount            int       `json:"daytrade_count"`
+	TradingBlocked           bool      `json:"trading_blocked"`
+	TransfersBlocked         bool      `json:"transfers_blocked"`
+	AccountBlocked           bool      `json:"account_blocked"`
+	TradeSuspendedByUser     bool      `json:"trade_suspended_by_user"`
+	ShortingEnabled          bool      `json:"shorting_enabled"`
+	CreatedAt                time.Time `json:"created_at"`
+}
+
+// AccountConfig holds the trading settings of the account
+type AccountConfig struct {
+	DTBPCheck              string `json:"dtbp_check"`
+	PDTCheck               string `json:"pdt_check"`
+	TradeConfirmEmail      string `json:"trade_confirm_email"`
+	SuspendTrade           bool   `json:"suspend_trade"`
+	NoShorting             bool   `json:"no_shorting"`
+	FractionalTrading      bool   `json:"fractional_trading"`
+	MaxMarginMultiplier    string `json:"max_margin_multiplier"`
+	MaxOptionsTradingLevel int    `json:"max_options_trading_level"`
+	PTPNoExceptionEntry    bool   `json:"ptp_no_exception_entry"`
+}
+
+func GetAccount() (Account, error) {
+	return defaultBroker.GetAccount()
+}
+
+func (b APIBroker) GetAccount() (Account, error) {
+	var account Account
+
+	_, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/account", nil)
+	if err != nil {
+		return account, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &account); err != nil {
+		return account, fmt.Errorf("error parsing account: %v", err)
+	}
+	return account, nil
+}
+
+// CanTrade reports whether the account accepts new orders
+func (a Account) CanTrade() bool {
+	return a.Status == "ACTIVE" && !a.TradingBlocked && !a.AccountBlocked && !a.TradeSuspendedByUser
+}
+
+func GetAccountConfig() (AccountConfig, error) {
+	return defaultBroker.GetAccountConfig()
+}
+
+func (b APIBroker) GetAccountConfig() (AccountConfig, error) {
+	var config AccountConfig
+
+	_, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/account/configurations", nil)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &config); err != nil {
+		return config, fmt.Errorf("error parsing account configuration: %v", err)
+	}
+	return config, nil
+}
+
+// UpdateAccountConfig writes config and returns the configuration now in effect. Fetch the
+// current configuration with GetAccountConfig first, as all fields are sent.
+func UpdateAccountConfig(config AccountConfig) (AccountConfig, error) {
+	return defaultBroker.UpdateAccountConfig(config)
+}
+
+func (b APIBroker) UpdateAccountConfig(config AccountConfig) (AccountConfig, error) {
+	switch config.DTBPCheck {
+	case "", "both", "entry", "exit":
+	default:
+		return config, fmt.Errorf("invalid dtbp_check %q. Expected both, entry or exit", config.DTBPCheck)
+	}
+	if config.MaxOptionsTradingLevel < 0 || config.MaxOptionsTradingLevel > 3 {
+		return config, fmt.Errorf("invalid max_options_trading_level %d. Expected 0 to 3", config.MaxOptionsTradingLevel)
+	}
+
+	var updated AccountConfig
+	_, bodyStr, err := b.APIRequestMethod("PATCH", b.tradingURL()+"/v2/account/configurations", config)
+	if err != nil {
+		return updated, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &updated); err != nil {
+		return updated, fmt.Errorf("error parsing account configuration: %v", err)
+	}
+	return updated, nil
+}
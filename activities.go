@@ -0,0 +1,179 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Activity types, FILL is the only trade activity
+const (
+	ActivityFill        = "FILL"
+	ActivityTransaction = "TRANS"
+	ActivityMisc        = "MISC"
+	ActivityDividend    = "DIV"
+	ActivityFee         = "FEE"
+	ActivityInterest    = "INT"
+	ActivityJournal     = "JNL"
+	ActivityJournalCash = "JNLC"
+	ActivityJournalSec  = "JNLS"
+	ActivityDeposit     = "CSD"
+	ActivityWithdrawal  = "CSW"
+	ActivityOptAssign   = "OPASN"
+	ActivityOptExpire   = "OPEXP"
+	ActivityOptExercise = "OPXRC"
+	ActivityOptTrade    = "OPTRD"
+	ActivityReorg       = "REORG"
+	ActivitySpinOff     = "SSO"
+	ActivitySplit       = "SSP"
+)
+
+// Largest page size of GET /v2/account/activities
+const maxActivitiesPage = 100
+
+// Activity is either a TradeActivity or a NonTradeActivity
+type Activity interface {
+	ActivityID() string
+	ActivityType() string
+	ActivityTime() time.Time
+}
+
+// TradeActivity is a fill or partial fill of an order
+type TradeActivity struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"activity_type"`
+	TransactionTime time.Time `json:"transaction_time"`
+	FillType        string    `json:"type"`
+	Price           Decimal   `json:"price"`
+	Qty             Decimal   `json:"qty"`
+	Side            string    `json:"side"`
+	Symbol          string    `json:"symbol"`
+	LeavesQty       Decimal   `json:"leaves_qty"`
+	OrderID         string    `json:"order_id"`
+	CumQty          Decimal   `json:"cum_qty"`
+	OrderStatus     string    `json:"order_status"`
+}
+
+// NonTradeActivity covers dividends, fees, transfers, journals and option assignments and expiries
+type NonTradeActivity struct {
+	ID             string  `json:"id"`
+	Type           string  `json:"activity_type"`
+	Date           string  `json:"date"`
+	NetAmount      Decimal `json:"net_amount"`
+	Symbol         string  `json:"symbol"`
+	Qty            Decimal `json:"qty"`
+	PerShareAmount Decimal `json:"per_share_amount"`
+	Description    string  `json:"description"`
+	Status         string  `json:"status"`
+}
+
+func (a TradeActivity) ActivityID() string      { return a.ID }
+func (a TradeActivity) ActivityType() string    { return a.Type }
+func (a TradeActivity) ActivityTime() time.Time { return a.TransactionTime }
+
+func (a NonTradeActivity) ActivityID() string   { return a.ID }
+func (a NonTradeActivity) ActivityType() string { return a.Type }
+
+// ActivityTime returns the activity date at midnight America/New_York
+func (a NonTradeActivity) ActivityTime() time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", a.Date, Market)
+	return t
+}
+
+type ActivitiesReq struct {
+	Types     []string
+	Date      string // YYYY-MM-DD, excludes After and Until
+	After     time.Time
+	Until     time.Time
+	Direction string // desc (default) or asc
+	PageToken string // ID of the last activity already received
+	Limit     int    // total number of activities to return, 0 for all
+}
+
+// decodeActivity picks the activity variant from the activity_type field
+func decodeActivity(raw json.RawMessage) (Activity, error) {
+	var head struct {
+		Type string `json:"activity_type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	if head.Type == ActivityFill {
+		var trade TradeActivity
+		err := json.Unmarshal(raw, &trade)
+		return trade, err
+	}
+	var nonTrade NonTradeActivity
+	err := json.Unmarshal(raw, &nonTrade)
+	return nonTrade, err
+}
+
+// GetAccountActivities returns the account activities matching the request, following page tokens
+func GetAccountActivities(actreq ActivitiesReq) ([]Activity, error) {
+	return defaultBroker.GetAccountActivities(actreq)
+}
+
+func (b APIBroker) GetAccountActivities(actreq ActivitiesReq) ([]Activity, error) {
+	if actreq.Date != "" && (!actreq.After.IsZero() || !actreq.Until.IsZero()) {
+		return nil, fmt.Errorf("Date cannot be combined with After or Until")
+	}
+
+	params := url.Values{}
+	params.Set("activity_types", strings.Join(actreq.Types, ","))
+	params.Set("date", actreq.Date)
+	if !actreq.After.IsZero() {
+		params.Set("after", actreq.After.Format(time.RFC3339))
+	}
+	if !actreq.Until.IsZero() {
+		params.Set("until", actreq.Until.Format(time.RFC3339))
+	}
+	params.Set("direction", actreq.Direction)
+	pageToken := actreq.PageToken
+
+	var activities []Activity
+	for {
+		pageSize := maxActivitiesPage
+		if actreq.Limit > 0 && actreq.Limit-len(activities) < pageSize {
+			pageSize = actreq.Limit - len(activities)
+		}
+		params.Set("page_size", strconv.Itoa(pageSize))
+		params.Set("page_token", pageToken)
+
+		_, bodyStr, err := b.APIRequestMethod("GET", buildURL(b.tradingURL(), "/v2/account/activities", params), nil)
+		if err != nil {
+			return activities, err
+		}
+
+		var page []json.RawMessage
+		if err := json.Unmarshal([]byte(bodyStr), &page); err != nil {
+			return activities, fmt.Errorf("error parsing activities: %v", err)
+		}
+		for _, raw := range page {
+			activity, err := decodeActivity(raw)
+			if err != nil {
+				return activities, fmt.Errorf("error parsing activity: %v", err)
+			}
+			activities = append(activities, activity)
+		}
+
+		if len(page) < pageSize || (actreq.Limit > 0 && len(activities) >= actreq.Limit) {
+			return activities, nil
+		}
+		pageToken = activities[len(activities)-1].ActivityID()
+	}
+}
+
+// Fills returns the trade activities of activities
+func Fills(activities []Activity) []TradeActivity {
+	var fills []TradeActivity
+	for _, activity := range activities {
+		if fill, ok := activity.(TradeActivity); ok {
+			fills = append(fills, fill)
+		}
+	}
+	return fills
+}
@@ -1,12 +1,14 @@
 package alpacaApiClient
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -16,6 +18,13 @@ import (
 var (
 	APIKeyID     string
 	APISecretKey string
+
+	// Base URLs of the trading and the market data API
+	TradingURL = PaperTradingURL
+	DataURL    = "https://data.alpaca.markets"
+
+	// Client used for all REST requests, replace its Transport to record or stub requests
+	HTTPClient = &http.Client{}
 )
 
 func init() {
@@ -60,7 +69,7 @@ func main() {
 	req.Header.Add("APCA-API-KEY-ID", config.APIKeyID)
 	req.Header.Add("APCA-API-SECRET-KEY", config.APISecretKey)
 
-	res, _ := http.DefaultClient.Do(req)
+	res, _ := HTTPClient.Do(req)
 
 	defer res.Body.Close()
 	body, _ := io.ReadAll(res.Body)
@@ -77,7 +86,7 @@ func main() {
 		req.Header.Add("APCA-API-KEY-ID", "PKAKUI1RYWHYUSLU49LW")
 		req.Header.Add("APCA-API-SECRET-KEY", "SCbRWgh5XAad3QTyS7fIksVK1e9H4x2ggjScE9c6")
 
-		res, _ = http.DefaultClient.Do(req)
+		res, _ = HTTPClient.Do(req)
 
 		defer res.Body.Close()
 		body, _ = io.ReadAll(res.Body)
@@ -94,7 +103,7 @@ func main() {
 		req.Header.Add("accept", "application/json")
 		req.Header.Add("content-type", "application/json")
 
-		res, _ = http.DefaultClient.Do(req)
+		res, _ = HTTPClient.Do(req)
 
 		defer res.Body.Close()
 		body, _ = io.ReadAll(res.Body)
@@ -127,18 +136,10 @@ func check(err error) {
 	}
 }
 
+// WriteJson writes content, which is already JSON, to path
 func WriteJson(path string, content string) {
-	// Open a file for writing
-	file, err := os.Create(path)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	defer file.Close()
-	// Encode the string as JSON and write it to the file
-	if err := json.NewEncoder(file).Encode(content); err != nil {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		fmt.Println(err)
-		return
 	}
 }
 
@@ -161,7 +162,15 @@ func LoadJson(path string) string {
 	return string(content)
 }
 
+// JsonToOptions reads the files of SaveOptions and the legacy {"options": [...]} layout with
+// camelCase snapshot keys.
+//
+// Deprecated: use LoadOptions, which returns errors and the file metadata.
 func JsonToOptions(path string) []Option {
+	if options, _, err := LoadOptions(path); err == nil {
+		return options
+	}
+
 	content := LoadJson(path)
 	if content == "" {
 		return nil
@@ -212,6 +221,7 @@ func JsonToOptions(path string) []Option {
 			ClosePrice:        getFloat64(optMap["close_price"]),
 			ClosePriceDate:    getString(optMap["close_price_date"]),
 			PPIND:             getBool(optMap["ppind"]),
+			Deliverables:      parseDeliverables(optMap["deliverables"]),
 
 			// Initialize all market data structures with empty values
 			DailyBar: &Bar{
@@ -423,6 +433,18 @@ type Trade struct {
 	Exchange  string    `json:"x"`
 }
 
+// Deliverable represents the shares or cash delivered when an option is exercised
+type Deliverable struct {
+	Type                 string  `json:"type"`
+	Symbol               string  `json:"symbol"`
+	AssetID              string  `json:"asset_id"`
+	Amount               float64 `json:"amount"`
+	AllocationPercentage float64 `json:"allocation_percentage"`
+	SettlementType       string  `json:"settlement_type"`
+	SettlementMethod     string  `json:"settlement_method"`
+	DelayedSettlement    bool    `json:"delayed_settlement"`
+}
+
 type Option struct {
 	// Basic contract information
 	ID                string  `json:"id"`
@@ -445,6 +467,9 @@ type Option struct {
 	ClosePriceDate    string  `json:"close_price_date"`
 	PPIND             bool    `json:"ppind"`
 
+	// Shares and cash delivered on exercise
+	Deliverables []Deliverable `json:"deliverables"`
+
 	// Market data
 	DailyBar     *Bar    `json:"dailyBar"`
 	PrevDailyBar *Bar    `json:"prevDailyBar"`
@@ -464,6 +489,10 @@ func (o Option) Print() string {
 }
 
 func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
+	return defaultBroker.GetOptions(optreq, nMax)
+}
+
+func (b APIBroker) GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 	print := true
 	var options []Option
 	var log string
@@ -476,6 +505,15 @@ func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 		}
 	}
 
+	// Reject underlyings without listed options before pulling any pages
+	asset, err := b.ValidateSymbol(optreq.Ticker)
+	if err != nil {
+		return nil, log, err
+	}
+	if !asset.OptionsEnabled() {
+		return nil, log, fmt.Errorf("%s has no options enabled", asset.Symbol)
+	}
+
 	if print {
 		fmt.Println("Pulling options for option request:")
 		fmt.Printf("ticker=%v\nContract_type=%v\nStrikeRange=%v\nDateRange=%v\n",
@@ -490,7 +528,8 @@ func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 	}
 
 	// Initial URL with all parameters
-	url := fmt.Sprintf("https://paper-api.alpaca.markets/v2/options/contracts?underlying_symbols=%s&show_deliverables=false&expiration_date_gte=%s&expiration_date_lte=%s&type=%s&strike_price_gte=%v&strike_price_lte=%v&page_token=%s&limit=1000",
+	url := fmt.Sprintf("%s/v2/options/contracts?underlying_symbols=%s&show_deliverables=true&expiration_date_gte=%s&expiration_date_lte=%s&type=%s&strike_price_gte=%v&strike_price_lte=%v&page_token=%s&limit=1000",
+		b.tradingURL(),
 		optreq.Ticker,
 		optreq.DateRange[0],
 		optreq.DateRange[1],
@@ -515,7 +554,7 @@ func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 			return options, log, fmt.Errorf("operation timed out after 5 minutes. Fetched %d options", len(options))
 		case <-tick:
 			// Make API request
-			_, bodyStr, err := APIRequest(url, 1)
+			_, bodyStr, err := b.APIRequest(url, 1)
 			if err != nil {
 				return nil, log, err
 			}
@@ -567,6 +606,7 @@ func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 					ClosePrice:        parseFloat64(getString(contractMap["close_price"])),
 					ClosePriceDate:    getString(contractMap["close_price_date"]),
 					PPIND:             getBool(contractMap["ppind"]),
+					Deliverables:      parseDeliverables(contractMap["deliverables"]),
 
 					// Initialize all pointer fields with empty but non-nil structs
 					DailyBar: &Bar{
@@ -654,7 +694,8 @@ func GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
 			}
 
 			// Update URL for next page
-			url = fmt.Sprintf("https://paper-api.alpaca.markets/v2/options/contracts?underlying_symbols=%s&show_deliverables=false&expiration_date_gte=%s&expiration_date_lte=%s&type=%s&strike_price_gte=%v&strike_price_lte=%v&page_token=%s&limit=1000",
+			url = fmt.Sprintf("%s/v2/options/contracts?underlying_symbols=%s&show_deliverables=true&expiration_date_gte=%s&expiration_date_lte=%s&type=%s&strike_price_gte=%v&strike_price_lte=%v&page_token=%s&limit=1000",
+				b.tradingURL(),
 				optreq.Ticker,
 				optreq.DateRange[0],
 				optreq.DateRange[1],
@@ -682,7 +723,8 @@ MARKET_DATA:
 
 	nextToken := ""
 	// Initial market data URL
-	marketDataURL := fmt.Sprintf("https://data.alpaca.markets/v1beta1/options/snapshots/%s?feed=indicative&limit=1000&page_token=%s&strike_price_gte=%v&strike_price_lte=%v&expiration_date_gte=%s&expiration_date_lte=%s&type=%s",
+	marketDataURL := fmt.Sprintf("%s/v1beta1/options/snapshots/%s?feed=indicative&limit=1000&page_token=%s&strike_price_gte=%v&strike_price_lte=%v&expiration_date_gte=%s&expiration_date_lte=%s&type=%s",
+		b.dataURL(),
 		optreq.Ticker,
 		nextToken,
 		optreq.StrikeRange[0],
@@ -698,7 +740,7 @@ MARKET_DATA:
 
 	// Continue fetching market data until no more pages
 	for {
-		_, bodyStr, err := APIRequest(marketDataURL, 1)
+		_, bodyStr, err := b.APIRequest(marketDataURL, 1)
 		if err != nil {
 			return options, log + "\nError fetching market data: " + err.Error(), nil
 		}
@@ -847,7 +889,8 @@ MARKET_DATA:
 		}
 
 		// Update URL with next page token
-		marketDataURL = fmt.Sprintf("https://data.alpaca.markets/v1beta1/options/snapshots/%s?feed=indicative&limit=1000&page_token=%s&strike_price_gte=%v&strike_price_lte=%v&expiration_date_gte=%s&expiration_date_lte=%s&type=%s",
+		marketDataURL = fmt.Sprintf("%s/v1beta1/options/snapshots/%s?feed=indicative&limit=1000&page_token=%s&strike_price_gte=%v&strike_price_lte=%v&expiration_date_gte=%s&expiration_date_lte=%s&type=%s",
+			b.dataURL(),
 			optreq.Ticker,
 			nextToken,
 			optreq.StrikeRange[0],
@@ -895,6 +938,79 @@ func parseBar(data map[string]interface{}) *Bar {
 	}
 }
 
+// Helper function to parse the deliverables array of an option contract
+func parseDeliverables(v interface{}) []Deliverable {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var deliverables []Deliverable
+	for _, item := range items {
+		d, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		deliverables = append(deliverables, Deliverable{
+			Type:                 getString(d["type"]),
+			Symbol:               getString(d["symbol"]),
+			AssetID:              getString(d["asset_id"]),
+			Amount:               getNumber(d["amount"]),
+			AllocationPercentage: getNumber(d["allocation_percentage"]),
+			SettlementType:       getString(d["settlement_type"]),
+			SettlementMethod:     getString(d["settlement_method"]),
+			DelayedSettlement:    getBool(d["delayed_settlement"]),
+		})
+	}
+	return deliverables
+}
+
+// Helper function to read a number that the API may send either as JSON number or as string
+func getNumber(v interface{}) float64 {
+	if s, ok := v.(string); ok {
+		return parseFloat64(s)
+	}
+	return getFloat64(v)
+}
+
+// Helper function to build an endpoint URL, skipping empty query parameters
+func buildURL(base, path string, params url.Values) string {
+	for key, values := range params {
+		if len(values) == 0 || values[0] == "" {
+			params.Del(key)
+		}
+	}
+	if len(params) == 0 {
+		return base + path
+	}
+	return base + path + "?" + params.Encode()
+}
+
+// Decimal is a number the trading API sends as string, e.g. "qty": "1.5"; null decodes to 0
+type Decimal float64
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	if str == "null" || str == "" {
+		*d = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return fmt.Errorf("invalid decimal %s: %v", string(data), err)
+	}
+	*d = Decimal(f)
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d Decimal) String() string {
+	return strconv.FormatFloat(float64(d), 'f', -1, 64)
+}
+
 // Helper function to parse string to float64
 func parseFloat64(s string) float64 {
 	f, err := strconv.ParseFloat(s, 64)
@@ -914,9 +1030,14 @@ func parseInt(s string) int {
 }
 
 func APIRequest(url string, iteration int) (string, string, error) {
+	return defaultBroker.APIRequest(url, iteration)
+}
+
+func (b APIBroker) APIRequest(url string, iteration int) (string, string, error) {
 	debug := false
 
-	if APIKeyID == "" || APISecretKey == "" {
+	keyID, secretKey := b.keys()
+	if keyID == "" || secretKey == "" {
 		return "", "", fmt.Errorf("APIKeyID or APISecretKey is not set")
 	}
 
@@ -933,11 +1054,11 @@ func APIRequest(url string, iteration int) (string, string, error) {
 	}
 
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("APCA-API-KEY-ID", APIKeyID)
-	req.Header.Add("APCA-API-SECRET-KEY", APISecretKey)
+	req.Header.Add("APCA-API-KEY-ID", keyID)
+	req.Header.Add("APCA-API-SECRET-KEY", secretKey)
 
 	var res *http.Response
-	res, err = http.DefaultClient.Do(req)
+	res, err = b.httpClient().Do(req)
 	if err != nil {
 		return "", "", fmt.Errorf("error making request: %v", err)
 	}
@@ -959,7 +1080,7 @@ func APIRequest(url string, iteration int) (string, string, error) {
 		}
 		waitTime := 5 * time.Second
 		time.Sleep(waitTime)
-		res, err = http.DefaultClient.Do(req)
+		res, err = b.httpClient().Do(req)
 		if err != nil {
 			return "", "", fmt.Errorf("error in retry attempt %d: %v", retryNr, err)
 		}
@@ -987,11 +1108,17 @@ func APIRequest(url string, iteration int) (string, string, error) {
 	}
 
 	// Validate JSON response
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal(body, &jsonResponse); err != nil {
+	var rawResponse interface{}
+	if err := json.Unmarshal(body, &rawResponse); err != nil {
 		return "", "", fmt.Errorf("invalid JSON response: %v", err)
 	}
 
+	// Lists like /v2/assets are returned as plain arrays
+	jsonResponse, ok := rawResponse.(map[string]interface{})
+	if !ok {
+		return res.Status, string(body), nil
+	}
+
 	// Check for API error messages
 	if errMsg, ok := jsonResponse["message"].(string); ok && errMsg != "" {
 		return "", "", fmt.Errorf("API error: %s", errMsg)
@@ -1014,6 +1141,76 @@ func APIRequest(url string, iteration int) (string, string, error) {
 	return res.Status, string(body), nil
 }
 
+// APIRequestMethod sends an authenticated request with an optional JSON payload and returns the
+// status and body. Unlike APIRequest it accepts any 2xx response, including empty ones, and only
+// retries on rate limiting, so orders are never submitted twice.
+func APIRequestMethod(method string, url string, payload interface{}) (string, string, error) {
+	return defaultBroker.APIRequestMethod(method, url, payload)
+}
+
+func (b APIBroker) APIRequestMethod(method string, url string, payload interface{}) (string, string, error) {
+	keyID, secretKey := b.keys()
+	if keyID == "" || secretKey == "" {
+		return "", "", fmt.Errorf("APIKeyID or APISecretKey is not set")
+	}
+
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return "", "", fmt.Errorf("error encoding payload: %v", err)
+		}
+	}
+
+	retryNr := 0
+	maxRetry := 12
+	for {
+		req, err := http.NewRequest(method, url, bytes.NewReader(data))
+		if err != nil {
+			return "", "", fmt.Errorf("error creating request: %v", err)
+		}
+
+		req.Header.Add("accept", "application/json")
+		req.Header.Add("APCA-API-KEY-ID", keyID)
+		req.Header.Add("APCA-API-SECRET-KEY", secretKey)
+		if payload != nil {
+			req.Header.Add("content-type", "application/json")
+		}
+
+		res, err := b.httpClient().Do(req)
+		if err != nil {
+			return "", "", fmt.Errorf("error making request: %v", err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return "", "", fmt.Errorf("error reading response body: %v", err)
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests && retryNr < maxRetry {
+			retryNr++
+			fmt.Printf("Rate limited, waiting for 5 seconds and retrying (%d)\n", retryNr)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			var apiErr struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+				return res.Status, string(body), fmt.Errorf("API error (%s): %s", res.Status, apiErr.Message)
+			}
+			return res.Status, string(body), fmt.Errorf("API error (%s): %s", res.Status, string(body))
+		}
+
+		return res.Status, string(body), nil
+	}
+}
+
 func stndrdth(n int) string {
 	switch math.Mod(float64(n), 10) {
 	case 1:
@@ -1032,8 +1229,13 @@ func URLoption(req OptionURLReq) (string, error) {
 }
 
 func MergeRequests(optreqs []OptionURLReq, nMax int) ([]Option, error) {
+	return defaultBroker.MergeRequests(optreqs, nMax)
+}
 
-	if APIKeyID == "" || APISecretKey == "" {
+func (b APIBroker) MergeRequests(optreqs []OptionURLReq, nMax int) ([]Option, error) {
+
+	keyID, secretKey := b.keys()
+	if keyID == "" || secretKey == "" {
 		return []Option{}, fmt.Errorf("APIKeyID or APISecretKey is not set")
 	}
 	log.Println("APIKeyID and APISecretKey are set")
@@ -1044,7 +1246,7 @@ func MergeRequests(optreqs []OptionURLReq, nMax int) ([]Option, error) {
 	var options_tmp []Option
 	var err error
 	for _, optreq := range optreqs {
-		options_tmp, msg, err = GetOptions(optreq, nMax)
+		options_tmp, msg, err = b.GetOptions(optreq, nMax)
 		if err != nil {
 			return []Option{}, fmt.Errorf("error getting options: %v", err)
 		}
@@ -1066,7 +1268,7 @@ func handleApiKeyInit() {
 		log.Println("Warning: APIKeyID or APISecretKey is not set by environment variables. Trying to load from config.json")
 		config, err := loadConfig()
 		if err != nil {
-			log.Println("Error loading config: %v", err)
+			log.Printf("Error loading config: %v", err)
 			return
 		}
 		APIKeyID = config.APIKeyID
@@ -1075,11 +1277,16 @@ func handleApiKeyInit() {
 }
 
 func SingleQuote(ticker string) (float64, error) {
-	if APIKeyID == "" || APISecretKey == "" {
+	return defaultBroker.SingleQuote(ticker)
+}
+
+func (b APIBroker) SingleQuote(ticker string) (float64, error) {
+	keyID, secretKey := b.keys()
+	if keyID == "" || secretKey == "" {
 		return 0, fmt.Errorf("APIKeyID or APISecretKey is not set")
 	}
 
-	url := fmt.Sprintf("https://data.alpaca.markets/v2/stocks/quotes/latest?symbols=%s", ticker)
+	url := fmt.Sprintf("%s/v2/stocks/quotes/latest?symbols=%s", b.dataURL(), ticker)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -1087,10 +1294,10 @@ func SingleQuote(ticker string) (float64, error) {
 	}
 
 	req.Header.Add("accept", "application/json")
-	req.Header.Add("APCA-API-KEY-ID", APIKeyID)
-	req.Header.Add("APCA-API-SECRET-KEY", APISecretKey)
+	req.Header.Add("APCA-API-KEY-ID", keyID)
+	req.Header.Add("APCA-API-SECRET-KEY", secretKey)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := b.httpClient().Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("Error making request: %v", err)
 	}
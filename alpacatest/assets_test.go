@@ -0,0 +1,79 @@
+package alpacatest_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+	"github.com/AaronGonsior/alpacaApiClient/alpacatest"
+)
+
+func TestValidateSymbol(t *testing.T) {
+	dir := alpacaApiClient.AssetCatalogDir
+	alpacaApiClient.AssetCatalogDir = t.TempDir()
+	defer func() { alpacaApiClient.AssetCatalogDir = dir }()
+
+	live := alpacatest.NewServer()
+	defer live.Close()
+	live.AddAssets(
+		alpacaApiClient.Asset{Class: "us_equity", Symbol: "AAPL", Status: "active", Tradable: true},
+		alpacaApiClient.Asset{Class: "us_equity", Symbol: "OLD", Status: "inactive"},
+	)
+	paper := alpacatest.NewServer()
+	defer paper.Close()
+	paper.AddAssets(alpacaApiClient.Asset{Class: "us_equity", Symbol: "AAPL", Status: "inactive"})
+
+	t.Run("SingleAsset", func(t *testing.T) {
+		if _, err := live.Broker().ValidateSymbol("aapl"); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(requests(live, "/v2/assets")); n != 0 {
+			t.Errorf("%d catalog requests, want a single asset request only", n)
+		}
+		if _, err := live.Broker().ValidateSymbol("NOPE"); err == nil || !strings.Contains(err.Error(), "unknown symbol") {
+			t.Errorf("expected an unknown symbol error, got %v", err)
+		}
+	})
+
+	t.Run("Catalog", func(t *testing.T) {
+		catalog, err := live.Broker().RefreshAssetCatalog()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(catalog.Assets) != 2 {
+			t.Fatalf("catalog of %d assets, want active and inactive ones", len(catalog.Assets))
+		}
+		before := len(live.Requests())
+		if _, err := live.Broker().ValidateSymbol("AAPL"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := live.Broker().ValidateSymbol("OLD"); err == nil || !strings.Contains(err.Error(), "not active") {
+			t.Errorf("expected an inactive asset error, got %v", err)
+		}
+		if n := len(live.Requests()) - before; n != 0 {
+			t.Errorf("%d requests, want lookups in the catalog", n)
+		}
+	})
+
+	t.Run("PerServer", func(t *testing.T) {
+		// The live catalog must not answer for the paper server
+		if _, err := paper.Broker().ValidateSymbol("AAPL"); err == nil || !strings.Contains(err.Error(), "not active") {
+			t.Errorf("expected the paper server's inactive asset, got %v", err)
+		}
+	})
+
+	t.Run("Disk", func(t *testing.T) {
+		files, _ := filepath.Glob(filepath.Join(alpacaApiClient.AssetCatalogDir, "*.json"))
+		if len(files) != 1 {
+			t.Fatalf("%d cached catalogs, want the live one", len(files))
+		}
+		catalog, err := alpacaApiClient.LoadAssetCatalog(files[0], alpacaApiClient.AssetCatalogMaxAge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := catalog.Lookup("OLD"); !ok {
+			t.Error("cached catalog misses OLD")
+		}
+	})
+}
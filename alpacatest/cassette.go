@@ -0,0 +1,248 @@
+package alpacatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Recorder modes
+const (
+	ModeReplay         = "replay"           // serve recorded responses only, unmatched requests fail
+	ModeRecord         = "record"           // forward every request and record it
+	ModeReplayOrRecord = "replay_or_record" // replay matches, forward and record the rest
+)
+
+// Headers replaced by RedactedValue before a request is written to a cassette
+var RedactedHeaders = []string{"APCA-API-KEY-ID", "APCA-API-SECRET-KEY", "Authorization"}
+
+const RedactedValue = "REDACTED"
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query"` // normalized, see normalizeQuery
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper recording request/response pairs to a cassette file and
+// replaying them, matched by method, path and normalized query. Identical requests are
+// replayed in recorded order, so retries and pagination replay like the original session.
+//
+// Record a session once against the live API and replay it in CI:
+//
+//	rec, err := alpacatest.NewRecorder("testdata/getoptions.json", alpacatest.ModeReplay)
+//	alpacaApiClient.HTTPClient = rec.Client()
+//	defer rec.Save()
+//	options, _, err := alpacaApiClient.GetOptions(optreq, -1)
+type Recorder struct {
+	Path string
+	Mode string
+
+	// Transport forwards requests in the record modes, http.DefaultTransport if nil
+	Transport http.RoundTripper
+
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+	recorded     bool
+}
+
+// NewRecorder loads the cassette at path. A missing cassette is an error in ModeReplay and
+// starts an empty cassette in the record modes.
+func NewRecorder(path string, mode string) (*Recorder, error) {
+	switch mode {
+	case ModeReplay, ModeRecord, ModeReplayOrRecord:
+	default:
+		return nil, fmt.Errorf("invalid recorder mode %q", mode)
+	}
+	r := &Recorder{Path: path, Mode: mode}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && mode == ModeReplayOrRecord {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %v", err)
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %v", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder as transport
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  normalizeQuery(req.URL.Query()),
+		Header: redact(req.Header),
+		Body:   string(body),
+	}
+
+	if r.Mode != ModeRecord {
+		if res, ok := r.replay(req, recorded); ok {
+			return res, nil
+		}
+		if r.Mode == ModeReplay {
+			return nil, fmt.Errorf("no recorded interaction for %s %s?%s", recorded.Method, recorded.Path, recorded.Query)
+		}
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	r.mutex.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request:  recorded,
+		Response: RecordedResponse{Status: res.StatusCode, Header: res.Header.Clone(), Body: string(resBody)},
+	})
+	r.used = append(r.used, true)
+	r.recorded = true
+	r.mutex.Unlock()
+	return res, nil
+}
+
+// replay returns the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		if interaction.Request.Method != recorded.Method || interaction.Request.Path != recorded.Path || interaction.Request.Query != recorded.Query {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, true
+	}
+	return nil, false
+}
+
+// Save writes the cassette if new interactions were recorded
+func (r *Recorder) Save() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.recorded {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.interactions); err != nil {
+		return fmt.Errorf("error encoding cassette: %v", err)
+	}
+	if err := os.WriteFile(r.Path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing cassette: %v", err)
+	}
+	r.recorded = false
+	return nil
+}
+
+// Unused returns the recorded interactions that were not replayed
+func (r *Recorder) Unused() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// normalizeQuery sorts the parameters and drops empty ones, e.g. the empty page_token of a
+// first page request
+func normalizeQuery(query url.Values) string {
+	normalized := url.Values{}
+	for key, values := range query {
+		for _, v := range values {
+			if v != "" {
+				normalized.Add(key, v)
+			}
+		}
+	}
+	for _, values := range normalized {
+		sort.Strings(values)
+	}
+	return normalized.Encode()
+}
+
+func redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	if redacted == nil {
+		redacted = http.Header{}
+	}
+	for _, name := range RedactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, RedactedValue)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,256 @@
+package alpacatest_test
+
+import (
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+	"github.com/AaronGonsior/alpacaApiClient/alpacatest"
+)
+
+// getoptions_paper.json is a session against the paper API and the market data API, recorded
+// with the keys of a paper account, which are redacted before it is written. getoptions.json
+// supplements it with a session against the fake server with known data, paged two items at a
+// time. Without keys -record only re-records the fake server cassette:
+//
+//	APCA_API_KEY_ID=... APCA_API_SECRET_KEY=... go test ./alpacatest -run Cassette -record
+var record = flag.Bool("record", false, "re-record the cassettes in testdata")
+
+const (
+	getOptionsCassette      = "testdata/getoptions.json"
+	paperGetOptionsCassette = "testdata/getoptions_paper.json"
+	paperGetOptionsRequest  = "testdata/getoptions_paper_request.json" // the request of the session
+)
+
+// isolateAssetCatalog keeps asset catalogs cached on this machine out of the recorded sessions
+func isolateAssetCatalog(t *testing.T) {
+	dir := alpacaApiClient.AssetCatalogDir
+	alpacaApiClient.AssetCatalogDir = t.TempDir()
+	t.Cleanup(func() { alpacaApiClient.AssetCatalogDir = dir })
+}
+
+func TestCassetteGetOptionsPaper(t *testing.T) {
+	isolateAssetCatalog(t)
+	keyID, secretKey := os.Getenv("APCA_API_KEY_ID"), os.Getenv("APCA_API_SECRET_KEY")
+	if *record && keyID != "" && secretKey != "" {
+		recordPaperGetOptions(t, keyID, secretKey)
+	}
+
+	data, err := os.ReadFile(paperGetOptionsRequest)
+	if os.IsNotExist(err) {
+		t.Skip("no paper API cassette recorded, record it with APCA_API_KEY_ID and APCA_API_SECRET_KEY of a paper account and -record")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var optreq alpacaApiClient.OptionURLReq
+	if err := json.Unmarshal(data, &optreq); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := alpacatest.NewRecorder(paperGetOptionsCassette, alpacatest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := alpacaApiClient.APIBroker{
+		TradingURL:   alpacaApiClient.PaperTradingURL,
+		DataURL:      "https://data.alpaca.markets",
+		APIKeyID:     "key",
+		APISecretKey: "secret",
+		Client:       rec.Client(),
+	}
+	options, _, err := broker.GetOptions(optreq, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) == 0 {
+		t.Fatal("no options replayed")
+	}
+	for _, o := range options {
+		if o.UnderlyingSymbol != optreq.Ticker || o.Type != optreq.Contract_type {
+			t.Errorf("%s is a %s on %s, want a %s on %s", o.Symbol, o.Type, o.UnderlyingSymbol, optreq.Contract_type, optreq.Ticker)
+		}
+		if o.ExpirationDate < optreq.DateRange[0] || o.ExpirationDate > optreq.DateRange[1] {
+			t.Errorf("%s expires %s, outside %v", o.Symbol, o.ExpirationDate, optreq.DateRange)
+		}
+	}
+
+	for _, interaction := range rec.Unused() {
+		t.Errorf("interaction not replayed: %s %s?%s", interaction.Request.Method, interaction.Request.Path, interaction.Request.Query)
+	}
+	if n := checkPageChain(t, paperGetOptionsCassette, "/v2/options/contracts"); n == 0 {
+		t.Error("no contract pages recorded")
+	}
+	checkPageChain(t, paperGetOptionsCassette, "/v1beta1/options/snapshots/"+optreq.Ticker)
+}
+
+// recordPaperGetOptions records the calls on AAPL expiring within two weeks from the paper API
+func recordPaperGetOptions(t *testing.T, keyID, secretKey string) {
+	today := time.Now().In(alpacaApiClient.Market)
+	optreq := alpacaApiClient.OptionURLReq{
+		Ticker:        "AAPL",
+		Contract_type: "call",
+		StrikeRange:   []int{0, 1000},
+		DateRange:     []string{today.Format("2006-01-02"), today.AddDate(0, 0, 14).Format("2006-01-02")},
+	}
+
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := alpacatest.NewRecorder(paperGetOptionsCassette, alpacatest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := alpacaApiClient.APIBroker{
+		TradingURL:   alpacaApiClient.PaperTradingURL,
+		DataURL:      "https://data.alpaca.markets",
+		APIKeyID:     keyID,
+		APISecretKey: secretKey,
+		Client:       rec.Client(),
+	}
+	if _, _, err := broker.GetOptions(optreq, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(paperGetOptionsCassette); strings.Contains(string(data), keyID) || strings.Contains(string(data), secretKey) {
+		os.Remove(paperGetOptionsCassette)
+		t.Fatal("cassette contains the API keys, removed it")
+	}
+
+	data, err := json.MarshalIndent(optreq, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paperGetOptionsRequest, append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCassetteGetOptions(t *testing.T) {
+	isolateAssetCatalog(t)
+	if *record {
+		recordGetOptions(t)
+	}
+
+	rec, err := alpacatest.NewRecorder(getOptionsCassette, alpacatest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := alpacaApiClient.APIBroker{
+		TradingURL:   alpacaApiClient.PaperTradingURL,
+		DataURL:      "https://data.alpaca.markets",
+		APIKeyID:     "key",
+		APISecretKey: "secret",
+		Client:       rec.Client(),
+	}
+	options, _, err := broker.GetOptions(chainReq, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := calls(5)
+	if len(options) != len(want) {
+		t.Fatalf("got %d options, want %d", len(options), len(want))
+	}
+	for i, o := range options {
+		if o.Symbol != want[i].Symbol {
+			t.Errorf("option %d is %s, want %s", i, o.Symbol, want[i].Symbol)
+			continue
+		}
+		if o.LatestQuote.BidPrice != want[i].LatestQuote.BidPrice || o.LatestQuote.AskPrice != want[i].LatestQuote.AskPrice {
+			t.Errorf("%s quoted %v/%v, want %v/%v", o.Symbol, o.LatestQuote.BidPrice, o.LatestQuote.AskPrice, want[i].LatestQuote.BidPrice, want[i].LatestQuote.AskPrice)
+		}
+		if o.Greeks.Delta != want[i].Greeks.Delta || o.ImpliedVol != want[i].ImpliedVol {
+			t.Errorf("%s has delta %v and IV %v, want %v and %v", o.Symbol, o.Greeks.Delta, o.ImpliedVol, want[i].Greeks.Delta, want[i].ImpliedVol)
+		}
+	}
+
+	if unused := rec.Unused(); len(unused) > 0 {
+		for _, interaction := range unused {
+			t.Errorf("interaction not replayed: %s %s?%s", interaction.Request.Method, interaction.Request.Path, interaction.Request.Query)
+		}
+	}
+	for _, path := range []string{"/v2/options/contracts", "/v1beta1/options/snapshots/AAPL"} {
+		if n := checkPageChain(t, getOptionsCassette, path); n != 3 {
+			t.Errorf("%d pages of %s recorded, want 3", n, path)
+		}
+	}
+}
+
+// checkPageChain checks that a cassette holds pages of path with redacted keys, each requested
+// with the next_page_token of the one before and the last without a next page, and returns
+// their number
+func checkPageChain(t *testing.T, cassette, path string) int {
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var interactions []alpacatest.Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		t.Fatal(err)
+	}
+
+	token := ""
+	n := 0
+	for _, interaction := range interactions {
+		if interaction.Request.Path != path {
+			continue
+		}
+		n++
+		query, _ := url.ParseQuery(interaction.Request.Query)
+		if query.Get("page_token") != token {
+			t.Errorf("%s page %d requested with page_token %q, want %q", path, n, query.Get("page_token"), token)
+		}
+		for _, name := range alpacatest.RedactedHeaders {
+			if v := interaction.Request.Header.Get(name); v != "" && v != alpacatest.RedactedValue {
+				t.Errorf("%s header %s is not redacted", path, name)
+			}
+		}
+		var body struct {
+			NextPageToken *string `json:"next_page_token"`
+		}
+		json.Unmarshal([]byte(interaction.Response.Body), &body)
+		token = ""
+		if body.NextPageToken != nil {
+			token = *body.NextPageToken
+		}
+	}
+	if token != "" {
+		t.Errorf("last page of %s has next_page_token %q", path, token)
+	}
+	return n
+}
+
+func recordGetOptions(t *testing.T) {
+	srv := alpacatest.NewServer()
+	defer srv.Close()
+	srv.MaxPageSize = 2
+	srv.AddOptions(calls(5)...)
+
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := alpacatest.NewRecorder(getOptionsCassette, alpacatest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Transport = srv.Client().Transport
+	broker := srv.Broker()
+	broker.Client = rec.Client()
+	if _, _, err := broker.GetOptions(chainReq, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(getOptionsCassette); strings.Contains(string(data), `"test"`) {
+		t.Fatal("cassette contains the API keys")
+	}
+}
@@ -0,0 +1,345 @@
+package alpacatest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+)
+
+// AddOptions adds option contracts with their snapshot data (bars, Greeks, IV, latest quote and
+// trade). Underlyings without an asset are added as active, options enabled assets.
+func (s *Server) AddOptions(options ...alpacaApiClient.Option) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, o := range options {
+		if o.ID == "" {
+			o.ID = newID()
+		}
+		replaced := false
+		for i := range s.options {
+			if s.options[i].Symbol == o.Symbol {
+				s.options[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			s.options = append(s.options, o)
+		}
+		if o.UnderlyingSymbol != "" && s.findAsset(o.UnderlyingSymbol) < 0 {
+			s.assets = append(s.assets, alpacaApiClient.Asset{
+				ID:         newID(),
+				Class:      "us_equity",
+				Exchange:   "NASDAQ",
+				Symbol:     o.UnderlyingSymbol,
+				Name:       o.UnderlyingSymbol,
+				Status:     "active",
+				Tradable:   true,
+				Attributes: []string{"options_enabled"},
+			})
+		}
+	}
+	sort.SliceStable(s.options, func(i, j int) bool { return s.options[i].Symbol < s.options[j].Symbol })
+}
+
+// AddAssets adds or replaces assets
+func (s *Server) AddAssets(assets ...alpacaApiClient.Asset) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, asset := range assets {
+		if asset.ID == "" {
+			asset.ID = newID()
+		}
+		if i := s.findAsset(asset.Symbol); i >= 0 {
+			s.assets[i] = asset
+		} else {
+			s.assets = append(s.assets, asset)
+		}
+	}
+}
+
+// SetQuote sets the latest quote of a stock or option symbol, used for quotes, snapshots and fills
+func (s *Server) SetQuote(symbol string, quote alpacaApiClient.Quote) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.quotes[symbol] = quote
+	for i := range s.options {
+		if s.options[i].Symbol == symbol {
+			q := quote
+			s.options[i].LatestQuote = &q
+		}
+	}
+}
+
+func (s *Server) findAsset(symbolOrID string) int {
+	for i, asset := range s.assets {
+		if asset.Symbol == symbolOrID || asset.ID == symbolOrID {
+			return i
+		}
+	}
+	return -1
+}
+
+// latestQuote returns the quote of a symbol from the quotes or the option snapshots
+func (s *Server) latestQuote(symbol string) (alpacaApiClient.Quote, bool) {
+	if quote, ok := s.quotes[symbol]; ok {
+		return quote, true
+	}
+	for _, o := range s.options {
+		if o.Symbol == symbol && o.LatestQuote != nil {
+			return *o.LatestQuote, true
+		}
+	}
+	return alpacaApiClient.Quote{}, false
+}
+
+// matchOptions returns the contracts passing the filters of the contract and snapshot endpoints
+func (s *Server) matchOptions(r *http.Request, underlying string) []alpacaApiClient.Option {
+	query := r.URL.Query()
+	underlyings := splitList(query.Get("underlying_symbols"))
+	if underlying != "" {
+		underlyings = []string{underlying}
+	}
+	symbols := splitList(query.Get("symbols"))
+	strikeGTE, _ := strconv.ParseFloat(query.Get("strike_price_gte"), 64)
+	strikeLTE, _ := strconv.ParseFloat(query.Get("strike_price_lte"), 64)
+
+	var matched []alpacaApiClient.Option
+	for _, o := range s.options {
+		if len(underlyings) > 0 && !contains(underlyings, o.UnderlyingSymbol) {
+			continue
+		}
+		if len(symbols) > 0 && !contains(symbols, o.Symbol) {
+			continue
+		}
+		if v := query.Get("type"); v != "" && v != o.Type {
+			continue
+		}
+		if v := query.Get("root_symbol"); v != "" && v != o.RootSymbol {
+			continue
+		}
+		if v := query.Get("expiration_date"); v != "" && v != o.ExpirationDate {
+			continue
+		}
+		if v := query.Get("expiration_date_gte"); v != "" && o.ExpirationDate < v {
+			continue
+		}
+		if v := query.Get("expiration_date_lte"); v != "" && o.ExpirationDate > v {
+			continue
+		}
+		if strikeGTE > 0 && o.StrikePrice < strikeGTE {
+			continue
+		}
+		if strikeLTE > 0 && o.StrikePrice > strikeLTE {
+			continue
+		}
+		matched = append(matched, o)
+	}
+	return matched
+}
+
+// contract returns the wire format of a contract, the API sends numbers as strings
+func contract(o alpacaApiClient.Option) map[string]interface{} {
+	deliverables := make([]map[string]interface{}, 0, len(o.Deliverables))
+	for _, d := range o.Deliverables {
+		deliverables = append(deliverables, map[string]interface{}{
+			"type":                  d.Type,
+			"symbol":                d.Symbol,
+			"asset_id":              d.AssetID,
+			"amount":                formatFloat(d.Amount),
+			"allocation_percentage": formatFloat(d.AllocationPercentage),
+			"settlement_type":       d.SettlementType,
+			"settlement_method":     d.SettlementMethod,
+			"delayed_settlement":    d.DelayedSettlement,
+		})
+	}
+	return map[string]interface{}{
+		"id":                  o.ID,
+		"symbol":              o.Symbol,
+		"name":                o.Name,
+		"status":              o.Status,
+		"tradable":            o.Tradable,
+		"expiration_date":     o.ExpirationDate,
+		"root_symbol":         o.RootSymbol,
+		"underlying_symbol":   o.UnderlyingSymbol,
+		"underlying_asset_id": o.UnderlyingAssetID,
+		"type":                o.Type,
+		"style":               o.Style,
+		"strike_price":        formatFloat(o.StrikePrice),
+		"multiplier":          strconv.Itoa(o.Multiplier),
+		"size":                strconv.Itoa(o.Size),
+		"open_interest":       strconv.Itoa(o.OpenInterest),
+		"open_interest_date":  o.OpenInterestDate,
+		"close_price":         formatFloat(o.ClosePrice),
+		"close_price_date":    o.ClosePriceDate,
+		"ppind":               o.PPIND,
+		"deliverables":        deliverables,
+	}
+}
+
+// snapshot returns the wire format of the market data of a contract
+func snapshot(o alpacaApiClient.Option) map[string]interface{} {
+	snap := map[string]interface{}{}
+	if o.LatestQuote != nil {
+		snap["latestQuote"] = o.LatestQuote
+	}
+	if o.LatestTrade != nil {
+		snap["latestTrade"] = o.LatestTrade
+	}
+	if o.MinuteBar != nil {
+		snap["minuteBar"] = o.MinuteBar
+	}
+	if o.DailyBar != nil {
+		snap["dailyBar"] = o.DailyBar
+	}
+	if o.PrevDailyBar != nil {
+		snap["prevDailyBar"] = o.PrevDailyBar
+	}
+	if o.Greeks != nil {
+		snap["greeks"] = o.Greeks
+	}
+	if o.ImpliedVol != 0 {
+		snap["impliedVolatility"] = o.ImpliedVol
+	}
+	return snap
+}
+
+func (s *Server) listContracts(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	matched := s.matchOptions(r, "")
+	start, end, next, err := s.page(r, len(matched), 100, 10000)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, 42210000, err.Error())
+		return
+	}
+	contracts := make([]map[string]interface{}, 0, end-start)
+	for _, o := range matched[start:end] {
+		contracts = append(contracts, contract(o))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"option_contracts": contracts, "next_page_token": next})
+}
+
+func (s *Server) getContract(w http.ResponseWriter, symbolOrID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, o := range s.options {
+		if o.Symbol == symbolOrID || o.ID == symbolOrID {
+			writeJSON(w, http.StatusOK, contract(o))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, 40410000, "option contract not found")
+}
+
+func (s *Server) listSnapshots(w http.ResponseWriter, r *http.Request, underlying string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if underlying == "" && r.URL.Query().Get("symbols") == "" {
+		writeError(w, http.StatusBadRequest, 40010001, "symbols is required")
+		return
+	}
+	matched := s.matchOptions(r, underlying)
+	start, end, next, err := s.page(r, len(matched), 100, 1000)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 40010001, err.Error())
+		return
+	}
+	snapshots := make(map[string]interface{}, end-start)
+	for _, o := range matched[start:end] {
+		snapshots[o.Symbol] = snapshot(o)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshots": snapshots, "next_page_token": next})
+}
+
+func (s *Server) latestQuotes(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	symbols := splitList(r.URL.Query().Get("symbols"))
+	if len(symbols) == 0 {
+		writeError(w, http.StatusBadRequest, 40010001, "symbols is required")
+		return
+	}
+	quotes := make(map[string]alpacaApiClient.Quote)
+	for _, symbol := range symbols {
+		if quote, ok := s.latestQuote(symbol); ok {
+			quotes[symbol] = quote
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"quotes": quotes})
+}
+
+func (s *Server) listAssets(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	query := r.URL.Query()
+	attributes := splitList(query.Get("attributes"))
+	assets := []alpacaApiClient.Asset{}
+	for _, asset := range s.assets {
+		if v := query.Get("status"); v != "" && v != asset.Status {
+			continue
+		}
+		if v := query.Get("asset_class"); v != "" && v != asset.Class {
+			continue
+		}
+		if v := query.Get("exchange"); v != "" && v != asset.Exchange {
+			continue
+		}
+		if len(attributes) > 0 && !containsAny(asset.Attributes, attributes) {
+			continue
+		}
+		assets = append(assets, asset)
+	}
+	writeJSON(w, http.StatusOK, assets)
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, symbolOrID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.findAsset(symbolOrID)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, 40410000, "asset not found for "+symbolOrID)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.assets[i])
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, v := range values {
+		if contains(list, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,69 @@
+package alpacatest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+	"github.com/AaronGonsior/alpacaApiClient/alpacatest"
+)
+
+func TestListOrdersPages(t *testing.T) {
+	srv := alpacatest.NewServer()
+	defer srv.Close()
+
+	// 503 orders submitted three at a time, so every page boundary falls within a group
+	base := time.Date(2025, 1, 10, 14, 30, 0, 0, time.UTC)
+	var added []alpacaApiClient.Order
+	for i := 0; i < 503; i++ {
+		submitted := base.Add(time.Duration(i/3) * time.Second)
+		added = append(added, alpacaApiClient.Order{
+			Symbol:      "AAPL",
+			Status:      "filled",
+			SubmittedAt: submitted,
+			CreatedAt:   submitted,
+			UpdatedAt:   submitted,
+		})
+	}
+	srv.AddOrders(added...)
+
+	for _, tc := range []struct {
+		name      string
+		limit     int
+		direction string
+		want      int
+	}{
+		{"All", 0, "", 503},
+		{"AllAsc", 0, "asc", 503},
+		{"Limit500", 500, "", 500},
+		{"Limit501", 501, "", 501},
+		{"Limit502", 502, "", 502},
+		{"Limit503", 503, "", 503},
+		{"Limit1000", 1000, "", 503},
+		{"Limit502Asc", 502, "asc", 502},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			orders, err := srv.Broker().ListOrders(alpacaApiClient.ListOrdersReq{Status: "all", Limit: tc.limit, Direction: tc.direction})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != tc.want {
+				t.Fatalf("got %d orders, want %d", len(orders), tc.want)
+			}
+			seen := make(map[string]bool)
+			for i, order := range orders {
+				if seen[order.ID] {
+					t.Fatalf("order %s returned twice", order.ID)
+				}
+				seen[order.ID] = true
+				if i == 0 {
+					continue
+				}
+				prev := orders[i-1].SubmittedAt
+				if (tc.direction == "asc" && order.SubmittedAt.Before(prev)) || (tc.direction != "asc" && order.SubmittedAt.After(prev)) {
+					t.Fatalf("order %d submitted at %v out of order after %v", i, order.SubmittedAt, prev)
+				}
+			}
+		})
+	}
+}
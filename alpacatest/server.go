@@ -0,0 +1,346 @@
+// Package alpacatest provides a fake Alpaca API server for tests and offline development.
+//
+// The server implements the option contract, snapshot, quote, asset, order, position and
+// account endpoints in memory, with the pagination, rate limit headers and error responses of
+// the real API. Use it through its broker and seed it with data:
+//
+//	srv := alpacatest.NewServer()
+//	defer srv.Close()
+//	broker := srv.Broker()
+//	srv.AddOptions(options...)
+//
+// Failures are injected per path with Fail, e.g. a 429 for the next two snapshot requests.
+// Recorder captures real sessions to cassette files and replays them instead.
+package alpacatest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+)
+
+// Server is an in-memory Alpaca API
+type Server struct {
+	*httptest.Server
+
+	// Credentials required in the APCA-API-KEY-ID and APCA-API-SECRET-KEY headers, when empty
+	// any non-empty key is accepted
+	KeyID     string
+	SecretKey string
+
+	// Requests allowed per minute before 429 responses, reported in the X-RateLimit headers
+	RateLimit int
+
+	// Fill market orders right away at the latest quote, like the paper trading API
+	FillMarketOrders bool
+
+	// Caps the page size of the contract and snapshot endpoints below the requested limit when
+	// set, so small data sets span several pages
+	MaxPageSize int
+
+	mutex       sync.Mutex
+	failures    []*Failure
+	requests    []Request
+	window      time.Time
+	windowCount int
+
+	assets    []alpacaApiClient.Asset
+	options   []alpacaApiClient.Option
+	quotes    map[string]alpacaApiClient.Quote
+	orders    []*alpacaApiClient.Order
+	positions map[string]*alpacaApiClient.Position
+	account   alpacaApiClient.Account
+}
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+// Failure makes matching requests fail. Status returns an error response with that code,
+// Malformed returns truncated JSON with status 200 and Delay holds the response back before
+// either or the regular response.
+type Failure struct {
+	Method    string // empty matches every method
+	Path      string // path prefix, empty matches every path
+	Status    int
+	Malformed bool
+	Delay     time.Duration
+	Times     int // number of requests affected, 0 until ClearFailures
+}
+
+// NewServer starts a server with an empty market and a funded account
+func NewServer() *Server {
+	s := &Server{
+		RateLimit:        200,
+		FillMarketOrders: true,
+		quotes:           make(map[string]alpacaApiClient.Quote),
+		positions:        make(map[string]*alpacaApiClient.Position),
+		account: alpacaApiClient.Account{
+			ID:                   newID(),
+			AccountNumber:        "PA0000000000",
+			Status:               "ACTIVE",
+			Currency:             "USD",
+			Cash:                 100000,
+			Multiplier:           1,
+			OptionsApprovedLevel: 3,
+			OptionsTradingLevel:  3,
+			ShortingEnabled:      true,
+			CreatedAt:            time.Now().UTC(),
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Broker returns an API broker talking to the server, authenticated with KeyID and SecretKey
+// or placeholder keys. The package URLs and keys of the client are left unchanged.
+func (s *Server) Broker() alpacaApiClient.APIBroker {
+	keyID, secret := s.KeyID, s.SecretKey
+	if keyID == "" {
+		keyID, secret = "test", "test"
+	}
+	return alpacaApiClient.APIBroker{
+		TradingURL:   s.URL,
+		DataURL:      s.URL,
+		APIKeyID:     keyID,
+		APISecretKey: secret,
+		Client:       s.Client(),
+	}
+}
+
+// Fail injects a failure for the matching requests
+func (s *Server) Fail(f Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+func (s *Server) ClearFailures() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = nil
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, 40010000, "error reading request body")
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Body: string(body)})
+	failure := s.matchFailure(r)
+	remaining, reset := s.rateLimit()
+	s.mutex.Unlock()
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.RateLimit))
+	if remaining >= 0 {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	} else {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+	}
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, 40110000, "request is not authorized")
+		return
+	}
+	if remaining < 0 {
+		writeError(w, http.StatusTooManyRequests, 42910000, "rate limit exceeded")
+		return
+	}
+
+	if failure != nil {
+		if failure.Delay > 0 {
+			select {
+			case <-time.After(failure.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if failure.Status != 0 {
+			writeError(w, failure.Status, failure.Status*100000, http.StatusText(failure.Status))
+			return
+		}
+		if failure.Malformed {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data": [{"id": "`))
+			return
+		}
+	}
+
+	s.route(w, r, body)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	keyID, secret := r.Header.Get("APCA-API-KEY-ID"), r.Header.Get("APCA-API-SECRET-KEY")
+	if keyID == "" || secret == "" {
+		return false
+	}
+	if s.KeyID != "" && (keyID != s.KeyID || secret != s.SecretKey) {
+		return false
+	}
+	return true
+}
+
+// matchFailure returns the first failure matching r and counts it down
+func (s *Server) matchFailure(r *http.Request) *Failure {
+	for i, f := range s.failures {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// rateLimit counts the request in the current one-minute window
+func (s *Server) rateLimit() (int, time.Time) {
+	now := time.Now()
+	if now.Sub(s.window) >= time.Minute {
+		s.window = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+	return s.RateLimit - s.windowCount, s.window.Add(time.Minute)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, body []byte) {
+	path := r.URL.Path
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case path == "/v2/options/contracts" && r.Method == "GET":
+		s.listContracts(w, r)
+	case strings.HasPrefix(path, "/v2/options/contracts/") && r.Method == "GET":
+		s.getContract(w, parts[3])
+	case path == "/v1beta1/options/snapshots" && r.Method == "GET":
+		s.listSnapshots(w, r, "")
+	case strings.HasPrefix(path, "/v1beta1/options/snapshots/") && r.Method == "GET":
+		s.listSnapshots(w, r, parts[3])
+	case (path == "/v1beta1/options/quotes/latest" || path == "/v2/stocks/quotes/latest") && r.Method == "GET":
+		s.latestQuotes(w, r)
+	case path == "/v2/assets" && r.Method == "GET":
+		s.listAssets(w, r)
+	case strings.HasPrefix(path, "/v2/assets/") && r.Method == "GET":
+		s.getAsset(w, parts[2])
+
+	case path == "/v2/account" && r.Method == "GET":
+		s.getAccount(w)
+	case path == "/v2/orders" && r.Method == "POST":
+		s.createOrder(w, body)
+	case path == "/v2/orders" && r.Method == "GET":
+		s.listOrders(w, r)
+	case path == "/v2/orders" && r.Method == "DELETE":
+		s.cancelAllOrders(w)
+	case path == "/v2/orders:by_client_order_id" && r.Method == "GET":
+		s.getOrderByClientID(w, r.URL.Query().Get("client_order_id"))
+	case len(parts) == 3 && parts[1] == "orders" && r.Method == "GET":
+		s.getOrder(w, parts[2])
+	case len(parts) == 3 && parts[1] == "orders" && r.Method == "PATCH":
+		s.replaceOrder(w, parts[2], body)
+	case len(parts) == 3 && parts[1] == "orders" && r.Method == "DELETE":
+		s.cancelOrder(w, parts[2])
+	case path == "/v2/positions" && r.Method == "GET":
+		s.listPositions(w)
+	case path == "/v2/positions" && r.Method == "DELETE":
+		s.closeAllPositions(w, r.URL.Query().Get("cancel_orders") == "true")
+	case len(parts) == 3 && parts[1] == "positions" && r.Method == "GET":
+		s.getPosition(w, parts[2])
+	case len(parts) == 3 && parts[1] == "positions" && r.Method == "DELETE":
+		s.closePosition(w, r, parts[2])
+	case len(parts) == 4 && parts[1] == "positions" && parts[3] == "exercise" && r.Method == "POST":
+		s.exercisePosition(w, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, 40410000, "endpoint not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	writeJSON(w, status, map[string]interface{}{"code": code, "message": message})
+}
+
+// page returns the slice bounds of the page selected by the limit and page_token parameters
+func (s *Server) page(r *http.Request, total int, defaultLimit int, maxLimit int) (int, int, interface{}, error) {
+	query := r.URL.Query()
+	limit := defaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return 0, 0, nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		limit = n
+	}
+	if s.MaxPageSize > 0 && limit > s.MaxPageSize {
+		limit = s.MaxPageSize
+	}
+
+	start := 0
+	if token := query.Get("page_token"); token != "" {
+		data, err := base64.StdEncoding.DecodeString(token)
+		if err == nil {
+			start, err = strconv.Atoi(string(data))
+		}
+		if err != nil || start < 0 || start > total {
+			return 0, 0, nil, fmt.Errorf("invalid page_token")
+		}
+	}
+
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	var next interface{}
+	if end < total {
+		next = base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	return start, end, next, nil
+}
+
+// newID returns a random UUID
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,241 @@
+package alpacatest_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+	"github.com/AaronGonsior/alpacaApiClient/alpacatest"
+)
+
+var chainReq = alpacaApiClient.OptionURLReq{
+	Ticker:        "AAPL",
+	Contract_type: "call",
+	StrikeRange:   []int{0, 1000},
+	DateRange:     []string{"2025-01-01", "2025-12-31"},
+}
+
+// calls returns n AAPL calls with strikes from 100 in steps of 5, the bid at a hundredth of
+// the strike and a delta falling with the strike
+func calls(n int) []alpacaApiClient.Option {
+	var options []alpacaApiClient.Option
+	for i := 0; i < n; i++ {
+		strike := float64(100 + 5*i)
+		bid := strike / 100
+		options = append(options, alpacaApiClient.Option{
+			Symbol:           fmt.Sprintf("AAPL250117C%08d", int(strike*1000)),
+			Name:             fmt.Sprintf("AAPL Jan 17 2025 %v Call", strike),
+			Status:           "active",
+			Tradable:         true,
+			ExpirationDate:   "2025-01-17",
+			RootSymbol:       "AAPL",
+			UnderlyingSymbol: "AAPL",
+			Type:             "call",
+			Style:            "american",
+			StrikePrice:      strike,
+			Multiplier:       100,
+			Size:             100,
+			LatestQuote:      &alpacaApiClient.Quote{BidPrice: bid, AskPrice: bid + 0.05, BidSize: 10, AskSize: 12},
+			Greeks:           &alpacaApiClient.Greeks{Delta: 0.9 - 0.1*float64(i)},
+			ImpliedVol:       0.25,
+		})
+	}
+	return options
+}
+
+// requests returns the requests to a path in the order received
+func requests(srv *alpacatest.Server, path string) []alpacatest.Request {
+	var matched []alpacatest.Request
+	for _, r := range srv.Requests() {
+		if r.Path == path {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func TestGetOptionsPages(t *testing.T) {
+	srv := alpacatest.NewServer()
+	defer srv.Close()
+	srv.MaxPageSize = 2
+	srv.AddOptions(calls(5)...)
+
+	options, _, err := srv.Broker().GetOptions(chainReq, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != 5 {
+		t.Fatalf("got %d options, want 5", len(options))
+	}
+	for i, o := range options {
+		want := calls(5)[i]
+		if o.Symbol != want.Symbol || o.StrikePrice != want.StrikePrice {
+			t.Errorf("option %d is %s strike %v, want %s strike %v", i, o.Symbol, o.StrikePrice, want.Symbol, want.StrikePrice)
+		}
+		if o.LatestQuote.BidPrice != want.LatestQuote.BidPrice || o.Greeks.Delta != want.Greeks.Delta {
+			t.Errorf("%s has bid %v and delta %v, want %v and %v", o.Symbol, o.LatestQuote.BidPrice, o.Greeks.Delta, want.LatestQuote.BidPrice, want.Greeks.Delta)
+		}
+	}
+
+	if n := len(requests(srv, "/v2/options/contracts")); n != 3 {
+		t.Errorf("%d contract requests, want 3", n)
+	}
+	if n := len(requests(srv, "/v1beta1/options/snapshots/AAPL")); n != 3 {
+		t.Errorf("%d snapshot requests, want 3", n)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	srv := alpacatest.NewServer()
+	defer srv.Close()
+	broker := srv.Broker()
+
+	t.Run("500", func(t *testing.T) {
+		srv.Fail(alpacatest.Failure{Path: "/v2/account", Status: http.StatusInternalServerError, Times: 1})
+		if _, err := broker.GetAccount(); err == nil || !strings.Contains(err.Error(), "500") {
+			t.Errorf("expected a 500 error, got %v", err)
+		}
+		if _, err := broker.GetAccount(); err != nil {
+			t.Errorf("failure did not end after one request: %v", err)
+		}
+	})
+
+	t.Run("429", func(t *testing.T) {
+		before := len(requests(srv, "/v2/account"))
+		srv.Fail(alpacatest.Failure{Path: "/v2/account", Status: http.StatusTooManyRequests, Times: 1})
+		account, err := broker.GetAccount()
+		if err != nil {
+			t.Fatalf("rate limited request was not retried: %v", err)
+		}
+		if account.Cash != 100000 {
+			t.Errorf("cash %v, want 100000", account.Cash)
+		}
+		if n := len(requests(srv, "/v2/account")) - before; n != 2 {
+			t.Errorf("%d requests, want the limited one and its retry", n)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		srv.Fail(alpacatest.Failure{Path: "/v2/positions", Malformed: true, Times: 1})
+		if _, err := broker.ListPositions(); err == nil {
+			t.Error("expected an error for a malformed response")
+		}
+		if _, err := broker.ListPositions(); err != nil {
+			t.Errorf("failure did not end after one request: %v", err)
+		}
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		srv.Fail(alpacatest.Failure{Path: "/v2/account", Delay: 200 * time.Millisecond, Times: 1})
+		start := time.Now()
+		if _, err := broker.GetAccount(); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("response after %v, want a delay of at least 200ms", elapsed)
+		}
+
+		impatient := broker
+		impatient.Client = &http.Client{Timeout: 100 * time.Millisecond}
+		srv.Fail(alpacatest.Failure{Path: "/v2/account", Delay: 2 * time.Second, Times: 1})
+		if _, err := impatient.GetAccount(); err == nil {
+			t.Error("expected a timeout")
+		}
+	})
+}
+
+func TestOrderRoundTrip(t *testing.T) {
+	srv := alpacatest.NewServer()
+	defer srv.Close()
+	broker := srv.Broker()
+	option := calls(1)[0]
+	option.LatestQuote = &alpacaApiClient.Quote{BidPrice: 2.40, AskPrice: 2.50}
+	srv.AddOptions(option)
+
+	order, err := broker.SubmitOrder(alpacaApiClient.OrderReq{
+		Symbol:         option.Symbol,
+		Qty:            2,
+		Side:           alpacaApiClient.SideBuy,
+		Type:           alpacaApiClient.OrderTypeMarket,
+		TimeInForce:    alpacaApiClient.TimeInForceDay,
+		PositionIntent: alpacaApiClient.PositionIntentBuyToOpen,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err = broker.GetOrder(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "filled" || order.FilledQty != 2 || order.FilledAvgPrice != 2.50 {
+		t.Fatalf("order %s filled %v at %v, want filled 2 at the ask 2.50", order.Status, order.FilledQty, order.FilledAvgPrice)
+	}
+
+	position, err := broker.GetPosition(option.Symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Qty != 2 || position.AvgEntryPrice != 2.50 {
+		t.Errorf("position of %v at %v, want 2 at 2.50", position.Qty, position.AvgEntryPrice)
+	}
+
+	closing, err := broker.ClosePosition(option.Symbol, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closing, err = broker.GetOrder(closing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closing.Side != alpacaApiClient.SideSell || closing.Status != "filled" || closing.FilledAvgPrice != 2.40 {
+		t.Errorf("closing order %s %s at %v, want a filled sell at the bid 2.40", closing.Side, closing.Status, closing.FilledAvgPrice)
+	}
+
+	positions, err := broker.ListPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 0 {
+		t.Errorf("%d positions left after closing", len(positions))
+	}
+	account, err := broker.GetAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := alpacaApiClient.Decimal(100000 - 2*2.50*100 + 2*2.40*100); account.Cash != want {
+		t.Errorf("cash %v, want %v", account.Cash, want)
+	}
+}
+
+func TestBrokersConcurrent(t *testing.T) {
+	var brokers []alpacaApiClient.APIBroker
+	for i := 0; i < 2; i++ {
+		srv := alpacatest.NewServer()
+		defer srv.Close()
+		srv.KeyID, srv.SecretKey = fmt.Sprintf("key%d", i), fmt.Sprintf("secret%d", i)
+		srv.SetCash(float64(1000 * (i + 1)))
+		brokers = append(brokers, srv.Broker())
+	}
+
+	errs := make(chan error, 2*20)
+	for i, broker := range brokers {
+		want := alpacaApiClient.Decimal(1000 * (i + 1))
+		for j := 0; j < 20; j++ {
+			go func(broker alpacaApiClient.APIBroker) {
+				account, err := broker.GetAccount()
+				if err == nil && account.Cash != want {
+					err = fmt.Errorf("cash %v, want %v", account.Cash, want)
+				}
+				errs <- err
+			}(broker)
+		}
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
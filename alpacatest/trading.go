@@ -0,0 +1,708 @@
+package alpacatest
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AaronGonsior/alpacaApiClient"
+)
+
+var occSymbol = regexp.MustCompile(`^[A-Z0-9]{1,6}\d{6}[CP]\d{8}$`)
+
+// orderRequest is the body of POST /v2/orders
+type orderRequest struct {
+	Symbol         string                  `json:"symbol"`
+	Qty            alpacaApiClient.Decimal `json:"qty"`
+	Notional       alpacaApiClient.Decimal `json:"notional"`
+	Side           string                  `json:"side"`
+	Type           string                  `json:"type"`
+	TimeInForce    string                  `json:"time_in_force"`
+	LimitPrice     alpacaApiClient.Decimal `json:"limit_price"`
+	StopPrice      alpacaApiClient.Decimal `json:"stop_price"`
+	TrailPrice     alpacaApiClient.Decimal `json:"trail_price"`
+	TrailPercent   alpacaApiClient.Decimal `json:"trail_percent"`
+	ExtendedHours  bool                    `json:"extended_hours"`
+	ClientOrderID  string                  `json:"client_order_id"`
+	OrderClass     string                  `json:"order_class"`
+	PositionIntent string                  `json:"position_intent"`
+	Legs           []struct {
+		Symbol         string                  `json:"symbol"`
+		RatioQty       alpacaApiClient.Decimal `json:"ratio_qty"`
+		Side           string                  `json:"side"`
+		PositionIntent string                  `json:"position_intent"`
+	} `json:"legs"`
+}
+
+// SetCash sets the cash balance of the account
+func (s *Server) SetCash(cash float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.account.Cash = alpacaApiClient.Decimal(cash)
+}
+
+// AddPosition opens a position at avgEntryPrice without an order, a negative qty is short
+func (s *Server) AddPosition(symbol string, qty float64, avgEntryPrice float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.positions[symbol] = &alpacaApiClient.Position{
+		AssetID:       newID(),
+		Symbol:        symbol,
+		AssetClass:    assetClass(symbol),
+		AvgEntryPrice: alpacaApiClient.Decimal(avgEntryPrice),
+		Qty:           alpacaApiClient.Decimal(qty),
+	}
+}
+
+// FillOrder fills qty of an open order at price, partially if qty is less than the remaining
+// quantity. Multi-leg orders fill every leg with its ratio at the leg quotes, the order price
+// defaults to the net of the legs.
+func (s *Server) FillOrder(orderID string, qty float64, price float64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order := s.findOrder(orderID)
+	if order == nil || !isOpen(order.Status) {
+		return false
+	}
+	s.fill(order, qty, price)
+	return true
+}
+
+// AddOrders adds orders as they are, e.g. with chosen submission times, without filling them
+func (s *Server) AddOrders(orders ...alpacaApiClient.Order) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, order := range orders {
+		if order.ID == "" {
+			order.ID = newID()
+		}
+		o := order
+		s.orders = append(s.orders, &o)
+	}
+}
+
+// Orders returns all orders, newest first
+func (s *Server) Orders() []alpacaApiClient.Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var orders []alpacaApiClient.Order
+	for i := len(s.orders) - 1; i >= 0; i-- {
+		orders = append(orders, *s.orders[i])
+	}
+	return orders
+}
+
+func assetClass(symbol string) string {
+	if occSymbol.MatchString(symbol) {
+		return "us_option"
+	}
+	return "us_equity"
+}
+
+func multiplier(symbol string) float64 {
+	if assetClass(symbol) == "us_option" {
+		return 100
+	}
+	return 1
+}
+
+func isOpen(status string) bool {
+	switch status {
+	case "new", "accepted", "pending_new", "partially_filled", "held":
+		return true
+	}
+	return false
+}
+
+func (s *Server) findOrder(orderID string) *alpacaApiClient.Order {
+	for _, order := range s.orders {
+		if order.ID == orderID {
+			return order
+		}
+	}
+	return nil
+}
+
+// fill executes qty of an order at price and books it on the position and the cash balance
+func (s *Server) fill(order *alpacaApiClient.Order, qty float64, price float64) {
+	remaining := float64(order.Qty - order.FilledQty)
+	if qty <= 0 || qty > remaining {
+		qty = remaining
+	}
+
+	if len(order.Legs) > 0 {
+		net := 0.0
+		for i := range order.Legs {
+			leg := &order.Legs[i]
+			legPrice := s.marketPrice(leg.Symbol, leg.Side)
+			if leg.Side == alpacaApiClient.SideBuy {
+				net += legPrice * float64(leg.RatioQty)
+			} else {
+				net -= legPrice * float64(leg.RatioQty)
+			}
+			s.book(leg.Symbol, leg.Side, qty*float64(leg.RatioQty), legPrice)
+			leg.FilledQty += alpacaApiClient.Decimal(qty * float64(leg.RatioQty))
+			leg.FilledAvgPrice = alpacaApiClient.Decimal(legPrice)
+			leg.Status = "partially_filled"
+			if leg.FilledQty >= leg.Qty {
+				leg.Status = "filled"
+				leg.FilledAt = time.Now().UTC()
+			}
+		}
+		if price == 0 {
+			price = net
+		}
+	} else {
+		s.book(order.Symbol, order.Side, qty, price)
+	}
+
+	filled := float64(order.FilledQty)
+	order.FilledAvgPrice = alpacaApiClient.Decimal((float64(order.FilledAvgPrice)*filled + price*qty) / (filled + qty))
+	order.FilledQty += alpacaApiClient.Decimal(qty)
+	order.UpdatedAt = time.Now().UTC()
+	if order.FilledQty >= order.Qty {
+		order.Status = "filled"
+		order.FilledAt = order.UpdatedAt
+	} else {
+		order.Status = "partially_filled"
+	}
+}
+
+// book applies a fill to the position and the cash balance
+func (s *Server) book(symbol string, side string, qty float64, price float64) {
+	signed := qty
+	if side == alpacaApiClient.SideSell {
+		signed = -qty
+	}
+	s.account.Cash -= alpacaApiClient.Decimal(signed * price * multiplier(symbol))
+
+	position, ok := s.positions[symbol]
+	if !ok {
+		position = &alpacaApiClient.Position{AssetID: newID(), Symbol: symbol, AssetClass: assetClass(symbol)}
+		s.positions[symbol] = position
+	}
+	held := float64(position.Qty)
+	switch {
+	case held == 0 || (held > 0) == (signed > 0):
+		// Opening or adding, average the entry price
+		position.AvgEntryPrice = alpacaApiClient.Decimal((float64(position.AvgEntryPrice)*math.Abs(held) + price*qty) / (math.Abs(held) + qty))
+	case math.Abs(signed) > math.Abs(held):
+		// Flipping sides, the remainder opens at price
+		position.AvgEntryPrice = alpacaApiClient.Decimal(price)
+	}
+	position.Qty = alpacaApiClient.Decimal(held + signed)
+	if position.Qty == 0 {
+		delete(s.positions, symbol)
+	}
+}
+
+// marketPrice returns the price a market order on symbol fills at: the ask for buys, the bid for sells
+func (s *Server) marketPrice(symbol string, side string) float64 {
+	quote, ok := s.latestQuote(symbol)
+	if !ok {
+		return 0
+	}
+	if side == alpacaApiClient.SideBuy && quote.AskPrice > 0 {
+		return quote.AskPrice
+	}
+	if side == alpacaApiClient.SideSell && quote.BidPrice > 0 {
+		return quote.BidPrice
+	}
+	return (quote.AskPrice + quote.BidPrice) / 2
+}
+
+// valued returns a position with the market value and P&L at the latest quote
+func (s *Server) valued(p alpacaApiClient.Position) alpacaApiClient.Position {
+	price := float64(p.AvgEntryPrice)
+	if quote, ok := s.latestQuote(p.Symbol); ok && quote.BidPrice > 0 && quote.AskPrice > 0 {
+		price = (quote.BidPrice + quote.AskPrice) / 2
+	}
+	qty := float64(p.Qty)
+	mult := multiplier(p.Symbol)
+
+	p.Exchange = "NASDAQ"
+	p.QtyAvailable = p.Qty
+	p.Side = "long"
+	if qty < 0 {
+		p.Side = "short"
+	}
+	p.CurrentPrice = alpacaApiClient.Decimal(price)
+	p.MarketValue = alpacaApiClient.Decimal(qty * price * mult)
+	p.CostBasis = alpacaApiClient.Decimal(qty * float64(p.AvgEntryPrice) * mult)
+	p.UnrealizedPL = p.MarketValue - p.CostBasis
+	if p.CostBasis != 0 {
+		p.UnrealizedPLPC = alpacaApiClient.Decimal(float64(p.UnrealizedPL) / math.Abs(float64(p.CostBasis)))
+	}
+	return p
+}
+
+func (s *Server) getAccount(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account := s.account
+	for _, p := range s.positions {
+		value := s.valued(*p).MarketValue
+		if value > 0 {
+			account.LongMarketValue += value
+		} else {
+			account.ShortMarketValue += value
+		}
+	}
+	account.Equity = account.Cash + account.LongMarketValue + account.ShortMarketValue
+	account.PortfolioValue = account.Equity
+	account.LastEquity = account.Equity
+	account.BuyingPower = account.Cash
+	account.RegTBuyingPower = account.Cash
+	account.NonMarginableBuyingPower = account.Cash
+	account.OptionsBuyingPower = account.Cash
+	writeJSON(w, http.StatusOK, account)
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, body []byte) {
+	var req orderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, 40010000, "request body format is invalid")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if req.ClientOrderID != "" {
+		for _, order := range s.orders {
+			if order.ClientOrderID == req.ClientOrderID {
+				writeError(w, http.StatusUnprocessableEntity, 40010001, "client_order_id must be unique")
+				return
+			}
+		}
+	}
+
+	switch {
+	case req.OrderClass != alpacaApiClient.OrderClassMLeg && req.Symbol == "":
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "symbol is required")
+		return
+	case req.OrderClass == alpacaApiClient.OrderClassMLeg && (len(req.Legs) < 2 || len(req.Legs) > 4):
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "mleg orders require 2 to 4 legs")
+		return
+	case req.OrderClass != alpacaApiClient.OrderClassMLeg && req.Side != alpacaApiClient.SideBuy && req.Side != alpacaApiClient.SideSell:
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "invalid side")
+		return
+	case req.Qty <= 0 && req.Notional <= 0:
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "qty or notional is required")
+		return
+	case req.Type == "" || req.TimeInForce == "":
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "type and time_in_force are required")
+		return
+	case (req.Type == alpacaApiClient.OrderTypeLimit || req.Type == alpacaApiClient.OrderTypeStopLimit) && req.LimitPrice <= 0:
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "limit_price is required for limit orders")
+		return
+	}
+	if req.OrderClass != alpacaApiClient.OrderClassMLeg && s.findAsset(req.Symbol) < 0 && !s.hasOption(req.Symbol) {
+		writeError(w, http.StatusUnprocessableEntity, 40010001, "asset "+req.Symbol+" not found")
+		return
+	}
+
+	now := time.Now().UTC()
+	order := &alpacaApiClient.Order{
+		ID:             newID(),
+		ClientOrderID:  req.ClientOrderID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		SubmittedAt:    now,
+		AssetID:        newID(),
+		Symbol:         req.Symbol,
+		AssetClass:     assetClass(req.Symbol),
+		Notional:       req.Notional,
+		Qty:            req.Qty,
+		OrderClass:     req.OrderClass,
+		Type:           req.Type,
+		Side:           req.Side,
+		PositionIntent: req.PositionIntent,
+		TimeInForce:    req.TimeInForce,
+		LimitPrice:     req.LimitPrice,
+		StopPrice:      req.StopPrice,
+		TrailPrice:     req.TrailPrice,
+		TrailPercent:   req.TrailPercent,
+		Status:         "new",
+		ExtendedHours:  req.ExtendedHours,
+	}
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = newID()
+	}
+	if order.Qty == 0 {
+		if price := s.marketPrice(req.Symbol, req.Side); price > 0 {
+			order.Qty = alpacaApiClient.Decimal(float64(req.Notional) / price)
+		}
+	}
+	for _, leg := range req.Legs {
+		order.AssetClass = "us_option"
+		order.Legs = append(order.Legs, alpacaApiClient.Order{
+			ID:             newID(),
+			ClientOrderID:  newID(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			SubmittedAt:    now,
+			Symbol:         leg.Symbol,
+			AssetClass:     assetClass(leg.Symbol),
+			Qty:            order.Qty * leg.RatioQty,
+			RatioQty:       leg.RatioQty,
+			OrderClass:     alpacaApiClient.OrderClassMLeg,
+			Type:           req.Type,
+			Side:           leg.Side,
+			PositionIntent: leg.PositionIntent,
+			TimeInForce:    req.TimeInForce,
+			Status:         "new",
+		})
+	}
+	s.orders = append(s.orders, order)
+
+	if s.FillMarketOrders && order.Type == alpacaApiClient.OrderTypeMarket {
+		if price := s.marketPrice(order.Symbol, order.Side); price > 0 || len(order.Legs) > 0 {
+			s.fill(order, float64(order.Qty), price)
+		}
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) hasOption(symbol string) bool {
+	for _, o := range s.options {
+		if o.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status == "" {
+		status = "open"
+	}
+	limit := 50
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			writeError(w, http.StatusUnprocessableEntity, 40010001, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	var after, until time.Time
+	if v := query.Get("after"); v != "" {
+		after, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	if v := query.Get("until"); v != "" {
+		until, _ = time.Parse(time.RFC3339Nano, v)
+	}
+	symbols := splitList(query.Get("symbols"))
+	nested := query.Get("nested") == "true"
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	orders := []alpacaApiClient.Order{}
+	for _, order := range s.orders {
+		if (status == "open" && !isOpen(order.Status)) || (status == "closed" && isOpen(order.Status)) {
+			continue
+		}
+		if !after.IsZero() && !order.SubmittedAt.After(after) {
+			continue
+		}
+		if !until.IsZero() && !order.SubmittedAt.Before(until) {
+			continue
+		}
+		if len(symbols) > 0 && !contains(symbols, order.Symbol) {
+			continue
+		}
+		if v := query.Get("side"); v != "" && v != order.Side {
+			continue
+		}
+		o := *order
+		if !nested {
+			o.Legs = nil
+		}
+		orders = append(orders, o)
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		if query.Get("direction") == "asc" {
+			return orders[i].SubmittedAt.Before(orders[j].SubmittedAt)
+		}
+		return orders[i].SubmittedAt.After(orders[j].SubmittedAt)
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, orderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order := s.findOrder(orderID)
+	if order == nil {
+		writeError(w, http.StatusNotFound, 40410000, "order not found for "+orderID)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) getOrderByClientID(w http.ResponseWriter, clientOrderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, order := range s.orders {
+		if order.ClientOrderID == clientOrderID {
+			writeJSON(w, http.StatusOK, order)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, 40410000, "order not found for "+clientOrderID)
+}
+
+func (s *Server) replaceOrder(w http.ResponseWriter, orderID string, body []byte) {
+	var req struct {
+		Qty           alpacaApiClient.Decimal `json:"qty"`
+		TimeInForce   string                  `json:"time_in_force"`
+		LimitPrice    alpacaApiClient.Decimal `json:"limit_price"`
+		StopPrice     alpacaApiClient.Decimal `json:"stop_price"`
+		Trail         alpacaApiClient.Decimal `json:"trail"`
+		ClientOrderID string                  `json:"client_order_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, 40010000, "request body format is invalid")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	old := s.findOrder(orderID)
+	if old == nil {
+		writeError(w, http.StatusNotFound, 40410000, "order not found for "+orderID)
+		return
+	}
+	if !isOpen(old.Status) || old.FilledQty > 0 {
+		writeError(w, http.StatusUnprocessableEntity, 42210000, "order is not replaceable, status "+old.Status)
+		return
+	}
+
+	now := time.Now().UTC()
+	order := *old
+	order.ID = newID()
+	order.ClientOrderID = newID()
+	order.CreatedAt, order.UpdatedAt, order.SubmittedAt = now, now, now
+	order.Replaces = old.ID
+	order.Legs = append([]alpacaApiClient.Order(nil), old.Legs...)
+	order.Status = "new"
+	if req.Qty > 0 {
+		order.Qty = req.Qty
+	}
+	if req.TimeInForce != "" {
+		order.TimeInForce = req.TimeInForce
+	}
+	if req.LimitPrice > 0 {
+		order.LimitPrice = req.LimitPrice
+	}
+	if req.StopPrice > 0 {
+		order.StopPrice = req.StopPrice
+	}
+	if req.Trail > 0 {
+		order.TrailPrice = req.Trail
+	}
+	if req.ClientOrderID != "" {
+		order.ClientOrderID = req.ClientOrderID
+	}
+
+	old.Status = "replaced"
+	old.ReplacedBy = order.ID
+	old.ReplacedAt = now
+	old.UpdatedAt = now
+	s.orders = append(s.orders, &order)
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, orderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order := s.findOrder(orderID)
+	if order == nil {
+		writeError(w, http.StatusNotFound, 40410000, "order not found for "+orderID)
+		return
+	}
+	if !isOpen(order.Status) {
+		writeError(w, http.StatusUnprocessableEntity, 42210000, "order is already in "+strings.ReplaceAll(order.Status, "_", " ")+" state")
+		return
+	}
+	s.cancel(order)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) cancel(order *alpacaApiClient.Order) {
+	now := time.Now().UTC()
+	order.Status = "canceled"
+	order.CanceledAt = now
+	order.UpdatedAt = now
+	for i := range order.Legs {
+		if isOpen(order.Legs[i].Status) {
+			order.Legs[i].Status = "canceled"
+			order.Legs[i].CanceledAt = now
+		}
+	}
+}
+
+func (s *Server) cancelAllOrders(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := []alpacaApiClient.CancelStatus{}
+	for _, order := range s.orders {
+		if isOpen(order.Status) {
+			s.cancel(order)
+			statuses = append(statuses, alpacaApiClient.CancelStatus{ID: order.ID, Status: http.StatusOK, Body: *order})
+		}
+	}
+	writeJSON(w, http.StatusMultiStatus, statuses)
+}
+
+func (s *Server) sortedPositions() []alpacaApiClient.Position {
+	positions := []alpacaApiClient.Position{}
+	for _, p := range s.positions {
+		positions = append(positions, s.valued(*p))
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions
+}
+
+func (s *Server) listPositions(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	writeJSON(w, http.StatusOK, s.sortedPositions())
+}
+
+func (s *Server) findPosition(symbolOrID string) *alpacaApiClient.Position {
+	if p, ok := s.positions[symbolOrID]; ok {
+		return p
+	}
+	for _, p := range s.positions {
+		if p.AssetID == symbolOrID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) getPosition(w http.ResponseWriter, symbolOrID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p := s.findPosition(symbolOrID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, 40410000, "position does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.valued(*p))
+}
+
+// liquidate submits a market order closing qty of a position
+func (s *Server) liquidate(p *alpacaApiClient.Position, qty float64) *alpacaApiClient.Order {
+	side := alpacaApiClient.SideSell
+	if p.Qty < 0 {
+		side = alpacaApiClient.SideBuy
+	}
+	now := time.Now().UTC()
+	order := &alpacaApiClient.Order{
+		ID:            newID(),
+		ClientOrderID: newID(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		SubmittedAt:   now,
+		AssetID:       p.AssetID,
+		Symbol:        p.Symbol,
+		AssetClass:    p.AssetClass,
+		Qty:           alpacaApiClient.Decimal(qty),
+		OrderClass:    alpacaApiClient.OrderClassSimple,
+		Type:          alpacaApiClient.OrderTypeMarket,
+		Side:          side,
+		TimeInForce:   alpacaApiClient.TimeInForceDay,
+		Status:        "new",
+	}
+	s.orders = append(s.orders, order)
+	if s.FillMarketOrders {
+		if price := s.marketPrice(p.Symbol, side); price > 0 {
+			s.fill(order, qty, price)
+		}
+	}
+	return order
+}
+
+func (s *Server) closePosition(w http.ResponseWriter, r *http.Request, symbolOrID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p := s.findPosition(symbolOrID)
+	if p == nil {
+		writeError(w, http.StatusNotFound, 40410000, "position does not exist")
+		return
+	}
+	held := math.Abs(float64(p.Qty))
+	qty := held
+	if v := r.URL.Query().Get("qty"); v != "" {
+		qty, _ = strconv.ParseFloat(v, 64)
+	} else if v := r.URL.Query().Get("percentage"); v != "" {
+		percentage, _ := strconv.ParseFloat(v, 64)
+		qty = held * percentage / 100
+	}
+	if qty <= 0 || qty > held {
+		writeError(w, http.StatusForbidden, 40310000, "insufficient qty available for order")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.liquidate(p, qty))
+}
+
+func (s *Server) closeAllPositions(w http.ResponseWriter, cancelOrders bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cancelOrders {
+		for _, order := range s.orders {
+			if isOpen(order.Status) {
+				s.cancel(order)
+			}
+		}
+	}
+	statuses := []alpacaApiClient.CloseStatus{}
+	for _, p := range s.sortedPositions() {
+		order := s.liquidate(s.positions[p.Symbol], math.Abs(float64(p.Qty)))
+		statuses = append(statuses, alpacaApiClient.CloseStatus{Symbol: p.Symbol, Status: http.StatusOK, Body: *order})
+	}
+	writeJSON(w, http.StatusMultiStatus, statuses)
+}
+
+// exercisePosition removes a long option position. The resulting stock position is not modeled.
+func (s *Server) exercisePosition(w http.ResponseWriter, symbolOrID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p := s.findPosition(symbolOrID)
+	if p == nil || p.AssetClass != "us_option" {
+		writeError(w, http.StatusNotFound, 40410000, "option position does not exist")
+		return
+	}
+	if p.Qty <= 0 {
+		writeError(w, http.StatusForbidden, 40310000, "only long option positions can be exercised")
+		return
+	}
+	delete(s.positions, p.Symbol)
+	w.WriteHeader(http.StatusOK)
+}
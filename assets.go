@@ -0,0 +1,292 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Lifetime of the asset catalogs used by ValidateSymbol and the directory they are cached in
+	// across runs, one file per trading API. Catalogs are only kept in memory when the directory
+	// is empty.
+	AssetCatalogDir    = defaultAssetCatalogDir()
+	AssetCatalogMaxAge = 24 * time.Hour
+
+	// Catalogs by trading URL, so live, paper and fake servers don't share their assets
+	assetCatalogs     = make(map[string]*AssetCatalog)
+	assetCatalogMutex sync.Mutex
+)
+
+func defaultAssetCatalogDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "alpacaApiClient")
+}
+
+type Asset struct {
+	ID                           string   `json:"id"`
+	Class                        string   `json:"class"`
+	Exchange                     string   `json:"exchange"`
+	Symbol                       string   `json:"symbol"`
+	Name                         string   `json:"name"`
+	Status                       string   `json:"status"`
+	Tradable                     bool     `json:"tradable"`
+	Marginable                   bool     `json:"marginable"`
+	Shortable                    bool     `json:"shortable"`
+	EasyToBorrow                 bool     `json:"easy_to_borrow"`
+	Fractionable                 bool     `json:"fractionable"`
+	MaintenanceMarginRequirement float64  `json:"maintenance_margin_requirement"`
+	Attributes                   []string `json:"attributes"`
+}
+
+type AssetsReq struct {
+	Status     string
+	AssetClass string
+	Exchange   string
+	Attributes []string
+}
+
+func (a Asset) HasAttribute(attribute string) bool {
+	for _, attr := range a.Attributes {
+		if attr == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Asset) OptionsEnabled() bool {
+	return a.HasAttribute("options_enabled")
+}
+
+// GetAssets lists all assets matching the request, e.g. AssetsReq{Status: "active", AssetClass: "us_equity"}
+func GetAssets(assetreq AssetsReq) ([]Asset, error) {
+	return defaultBroker.GetAssets(assetreq)
+}
+
+func (b APIBroker) GetAssets(assetreq AssetsReq) ([]Asset, error) {
+	params := url.Values{}
+	params.Set("status", assetreq.Status)
+	params.Set("asset_class", assetreq.AssetClass)
+	params.Set("exchange", assetreq.Exchange)
+	params.Set("attributes", strings.Join(assetreq.Attributes, ","))
+
+	_, bodyStr, err := b.APIRequestMethod("GET", buildURL(b.tradingURL(), "/v2/assets", params), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []Asset
+	if err := json.Unmarshal([]byte(bodyStr), &assets); err != nil {
+		return nil, fmt.Errorf("error parsing assets: %v", err)
+	}
+	return assets, nil
+}
+
+// GetAsset fetches a single asset by symbol or asset ID
+func GetAsset(symbolOrID string) (Asset, error) {
+	return defaultBroker.GetAsset(symbolOrID)
+}
+
+func (b APIBroker) GetAsset(symbolOrID string) (Asset, error) {
+	asset, found, err := b.getAsset(symbolOrID)
+	if err == nil && !found {
+		err = fmt.Errorf("symbol %q not found", symbolOrID)
+	}
+	return asset, err
+}
+
+// getAsset reports a missing asset as not found instead of an error
+func (b APIBroker) getAsset(symbolOrID string) (Asset, bool, error) {
+	var asset Asset
+
+	status, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/assets/"+url.PathEscape(symbolOrID), nil)
+	if strings.HasPrefix(status, "404") {
+		return asset, false, nil
+	}
+	if err != nil {
+		return asset, false, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &asset); err != nil {
+		return asset, false, fmt.Errorf("error parsing asset: %v", err)
+	}
+	return asset, true, nil
+}
+
+// AssetCatalog is a snapshot of all US equities of any status indexed by symbol
+type AssetCatalog struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Assets    []Asset   `json:"assets"`
+
+	bySymbol map[string]int
+}
+
+func (c *AssetCatalog) index() {
+	c.bySymbol = make(map[string]int, len(c.Assets))
+	for i, asset := range c.Assets {
+		c.bySymbol[asset.Symbol] = i
+	}
+}
+
+func (c *AssetCatalog) Lookup(symbol string) (Asset, bool) {
+	i, ok := c.bySymbol[strings.ToUpper(symbol)]
+	if !ok {
+		return Asset{}, false
+	}
+	return c.Assets[i], true
+}
+
+// Save writes the catalog to path
+func (c *AssetCatalog) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating asset catalog file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(c); err != nil {
+		return fmt.Errorf("error encoding asset catalog: %v", err)
+	}
+	return nil
+}
+
+// LoadAssetCatalog reads the catalog at path and refreshes it from the API when the file
+// is missing or older than maxAge. An empty path always fetches the catalog without caching it,
+// a failure to write the cache file is only logged.
+func LoadAssetCatalog(path string, maxAge time.Duration) (*AssetCatalog, error) {
+	return defaultBroker.LoadAssetCatalog(path, maxAge)
+}
+
+func (b APIBroker) LoadAssetCatalog(path string, maxAge time.Duration) (*AssetCatalog, error) {
+	if catalog, err := readAssetCatalog(path, maxAge); err == nil {
+		return catalog, nil
+	}
+
+	assets, err := b.GetAssets(AssetsReq{AssetClass: "us_equity"})
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing asset catalog: %v", err)
+	}
+	catalog := AssetCatalog{FetchedAt: time.Now(), Assets: assets}
+	catalog.index()
+
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Printf("Warning: asset catalog not cached: %v", err)
+		} else if err := catalog.Save(path); err != nil {
+			log.Printf("Warning: asset catalog not cached: %v", err)
+		}
+	}
+	return &catalog, nil
+}
+
+// readAssetCatalog reads the catalog at path if it is younger than maxAge
+func readAssetCatalog(path string, maxAge time.Duration) (*AssetCatalog, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no asset catalog path")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var catalog AssetCatalog
+	if err := json.NewDecoder(file).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("error parsing asset catalog: %v", err)
+	}
+	if time.Since(catalog.FetchedAt) >= maxAge {
+		return nil, fmt.Errorf("asset catalog is older than %v", maxAge)
+	}
+	catalog.index()
+	return &catalog, nil
+}
+
+// assetCatalogPath returns the cache file of the broker's catalog in AssetCatalogDir, named
+// after the host of the trading URL
+func (b APIBroker) assetCatalogPath() string {
+	if AssetCatalogDir == "" {
+		return ""
+	}
+	name := b.tradingURL()
+	if u, err := url.Parse(name); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	name = strings.NewReplacer(":", "_", "/", "_").Replace(name)
+	return filepath.Join(AssetCatalogDir, "assets_"+name+".json")
+}
+
+// RefreshAssetCatalog downloads the catalog of all US equities, caches it in AssetCatalogDir and
+// uses it for ValidateSymbol
+func RefreshAssetCatalog() (*AssetCatalog, error) {
+	return defaultBroker.RefreshAssetCatalog()
+}
+
+func (b APIBroker) RefreshAssetCatalog() (*AssetCatalog, error) {
+	catalog, err := b.LoadAssetCatalog(b.assetCatalogPath(), 0)
+	if err != nil {
+		return nil, err
+	}
+	assetCatalogMutex.Lock()
+	assetCatalogs[b.tradingURL()] = catalog
+	assetCatalogMutex.Unlock()
+	return catalog, nil
+}
+
+// cachedAssetCatalog returns the broker's catalog from memory or AssetCatalogDir if it is younger
+// than AssetCatalogMaxAge, nil otherwise
+func (b APIBroker) cachedAssetCatalog() *AssetCatalog {
+	assetCatalogMutex.Lock()
+	defer assetCatalogMutex.Unlock()
+
+	key := b.tradingURL()
+	if catalog := assetCatalogs[key]; catalog != nil && time.Since(catalog.FetchedAt) < AssetCatalogMaxAge {
+		return catalog
+	}
+	delete(assetCatalogs, key)
+	catalog, err := readAssetCatalog(b.assetCatalogPath(), AssetCatalogMaxAge)
+	if err != nil {
+		return nil
+	}
+	assetCatalogs[key] = catalog
+	return catalog
+}
+
+// ValidateSymbol checks that symbol is a known, active and tradable asset. It looks the symbol up
+// in a cached catalog of the broker's trading API, see RefreshAssetCatalog, and requests the
+// single asset when there is none or the symbol is missing from it.
+func ValidateSymbol(symbol string) (Asset, error) {
+	return defaultBroker.ValidateSymbol(symbol)
+}
+
+func (b APIBroker) ValidateSymbol(symbol string) (Asset, error) {
+	asset, ok := Asset{}, false
+	if catalog := b.cachedAssetCatalog(); catalog != nil {
+		asset, ok = catalog.Lookup(symbol)
+	}
+	if !ok {
+		var err error
+		asset, ok, err = b.getAsset(strings.ToUpper(symbol))
+		if err != nil {
+			return asset, err
+		}
+		if !ok {
+			return asset, fmt.Errorf("unknown symbol %q", symbol)
+		}
+	}
+	if asset.Status != "active" {
+		return asset, fmt.Errorf("%s is not active (status %s)", asset.Symbol, asset.Status)
+	}
+	if !asset.Tradable {
+		return asset, fmt.Errorf("%s is not tradable", asset.Symbol)
+	}
+	return asset, nil
+}
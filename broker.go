@@ -0,0 +1,185 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// MarketData provides latest prices
+type MarketData interface {
+	SingleQuote(ticker string) (float64, error)
+}
+
+// OptionChainSource provides option contracts with their market data
+type OptionChainSource interface {
+	GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error)
+}
+
+// AccountReader provides the account, its positions and activities
+type AccountReader interface {
+	GetAccount() (Account, error)
+	ListPositions() ([]Position, error)
+	GetPosition(symbolOrAssetID string) (Position, error)
+	GetAccountActivities(actreq ActivitiesReq) ([]Activity, error)
+}
+
+// Trader places and manages orders and closes positions
+type Trader interface {
+	SubmitOrder(orderreq OrderReq) (Order, error)
+	GetOrder(orderID string) (Order, error)
+	GetOrderByClientID(clientOrderID string) (Order, error)
+	ListOrders(listreq ListOrdersReq) ([]Order, error)
+	ReplaceOrder(orderID string, replacereq ReplaceOrderReq) (Order, error)
+	CancelOrder(orderID string) error
+	CancelAllOrders() ([]CancelStatus, error)
+	ClosePosition(symbolOrAssetID string, qty float64, percentage float64) (Order, error)
+	CloseAllPositions(cancelOrders bool) ([]CloseStatus, error)
+	ExercisePosition(symbolOrContractID string) error
+}
+
+// Broker is a complete backend for a trading bot: APIBroker for the live and paper API or a
+// fake server, Simulator for local backtests
+type Broker interface {
+	MarketData
+	OptionChainSource
+	AccountReader
+	Trader
+}
+
+var (
+	_ Broker = APIBroker{}
+	_ Broker = (*Simulator)(nil)
+)
+
+// APIBroker sends requests with its own URLs, keys and HTTP client, so brokers for different
+// accounts or servers can be used concurrently. Empty fields fall back to TradingURL, DataURL,
+// the API keys and HTTPClient at the time of the request. The package functions use a zero
+// APIBroker.
+type APIBroker struct {
+	TradingURL   string
+	DataURL      string
+	APIKeyID     string
+	APISecretKey string
+	Client       *http.Client
+}
+
+var defaultBroker APIBroker
+
+func (b APIBroker) tradingURL() string {
+	if b.TradingURL != "" {
+		return b.TradingURL
+	}
+	return TradingURL
+}
+
+func (b APIBroker) dataURL() string {
+	if b.DataURL != "" {
+		return b.DataURL
+	}
+	return DataURL
+}
+
+func (b APIBroker) keys() (string, string) {
+	if b.APIKeyID != "" || b.APISecretKey != "" {
+		return b.APIKeyID, b.APISecretKey
+	}
+	return APIKeyID, APISecretKey
+}
+
+func (b APIBroker) httpClient() *http.Client {
+	if b.Client != nil {
+		return b.Client
+	}
+	return HTTPClient
+}
+
+// Backends of BrokerConfig
+const (
+	BackendLive  = "live"
+	BackendPaper = "paper"
+	BackendSim   = "sim"
+)
+
+const (
+	LiveTradingURL  = "https://api.alpaca.markets"
+	PaperTradingURL = "https://paper-api.alpaca.markets"
+)
+
+// BrokerConfig selects the backend of NewBroker, e.g. read from a JSON file:
+//
+//	{"backend": "sim", "sim": {"cash": 100000, "slippage": 0.001, "commission_per_contract": 0.65}}
+type BrokerConfig struct {
+	Backend string `json:"backend"`
+
+	// Keys of the live and paper backends, the package keys are used when empty
+	APIKeyID     string `json:"api_key_id"`
+	APISecretKey string `json:"api_secret_key"`
+
+	// Override the default URLs of the live and paper backends, e.g. to use a fake server. An
+	// empty DataURL uses the package DataURL.
+	TradingURL string `json:"trading_url"`
+	DataURL    string `json:"data_url"`
+
+	Sim SimConfig `json:"sim"`
+}
+
+// SimConfig sets up the Simulator of the sim backend
+type SimConfig struct {
+	Cash                  float64   `json:"cash"`
+	Start                 time.Time `json:"start"` // now when zero
+	Slippage              float64   `json:"slippage"`
+	CommissionPerShare    float64   `json:"commission_per_share"`
+	CommissionPerContract float64   `json:"commission_per_contract"`
+	MinCommission         float64   `json:"min_commission"`
+	FillRatio             float64   `json:"fill_ratio"`
+}
+
+func LoadBrokerConfig(path string) (BrokerConfig, error) {
+	var config BrokerConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading broker config: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error parsing broker config: %v", err)
+	}
+	return config, nil
+}
+
+// NewBroker returns the backend selected by the config. The live and paper backends keep the
+// package URLs and keys unchanged, their settings are held by the returned APIBroker.
+func NewBroker(config BrokerConfig) (Broker, error) {
+	switch config.Backend {
+	case BackendLive, BackendPaper:
+		broker := APIBroker{
+			TradingURL:   PaperTradingURL,
+			DataURL:      config.DataURL,
+			APIKeyID:     config.APIKeyID,
+			APISecretKey: config.APISecretKey,
+		}
+		if config.Backend == BackendLive {
+			broker.TradingURL = LiveTradingURL
+		}
+		if config.TradingURL != "" {
+			broker.TradingURL = config.TradingURL
+		}
+		return broker, nil
+
+	case BackendSim:
+		start := config.Sim.Start
+		if start.IsZero() {
+			start = time.Now()
+		}
+		sim := NewSimulator(config.Sim.Cash, start)
+		sim.Slippage = config.Sim.Slippage
+		sim.CommissionPerShare = config.Sim.CommissionPerShare
+		sim.CommissionPerContract = config.Sim.CommissionPerContract
+		sim.MinCommission = config.Sim.MinCommission
+		sim.FillRatio = config.Sim.FillRatio
+		return sim, nil
+	}
+	return nil, fmt.Errorf("unknown broker backend %q, use %s, %s or %s", config.Backend, BackendLive, BackendPaper, BackendSim)
+}
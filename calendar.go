@@ -0,0 +1,273 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+	_ "time/tzdata" // keeps America/New_York available on hosts without a zoneinfo database
+)
+
+// Market is the time zone all calendar helpers work in
+var Market = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+type Clock struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
+
+// CalendarDay is a trading day as returned by /v2/calendar, times are in America/New_York
+type CalendarDay struct {
+	Date           string `json:"date"`
+	Open           string `json:"open"`
+	Close          string `json:"close"`
+	SessionOpen    string `json:"session_open"`
+	SessionClose   string `json:"session_close"`
+	SettlementDate string `json:"settlement_date"`
+}
+
+func GetClock() (Clock, error) {
+	return defaultBroker.GetClock()
+}
+
+func (b APIBroker) GetClock() (Clock, error) {
+	var clock Clock
+
+	_, bodyStr, err := b.APIRequest(b.tradingURL()+"/v2/clock", 1)
+	if err != nil {
+		return clock, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &clock); err != nil {
+		return clock, fmt.Errorf("error parsing clock: %v", err)
+	}
+	return clock, nil
+}
+
+// GetCalendar returns the trading days between start and end (YYYY-MM-DD, inclusive)
+func GetCalendar(start, end string) ([]CalendarDay, error) {
+	return defaultBroker.GetCalendar(start, end)
+}
+
+func (b APIBroker) GetCalendar(start, end string) ([]CalendarDay, error) {
+	params := url.Values{}
+	params.Set("start", start)
+	params.Set("end", end)
+
+	_, bodyStr, err := b.APIRequest(buildURL(b.tradingURL(), "/v2/calendar", params), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []CalendarDay
+	if err := json.Unmarshal([]byte(bodyStr), &days); err != nil {
+		return nil, fmt.Errorf("error parsing calendar: %v", err)
+	}
+	return days, nil
+}
+
+// Session is the regular trading session of one day
+type Session struct {
+	Date  time.Time
+	Open  time.Time
+	Close time.Time
+}
+
+// EarlyClose reports whether the session closes before the regular 16:00
+func (s Session) EarlyClose() bool {
+	return s.Close.Hour() < 16
+}
+
+// Calendar answers market hours questions offline from a list of fetched sessions
+type Calendar struct {
+	Start    string        `json:"start"`
+	End      string        `json:"end"`
+	Days     []CalendarDay `json:"days"`
+	sessions []Session
+}
+
+func NewCalendar(start, end string, days []CalendarDay) (*Calendar, error) {
+	c := &Calendar{Start: start, End: end, Days: days}
+	if err := c.parse(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Calendar) parse() error {
+	c.sessions = make([]Session, 0, len(c.Days))
+	for _, day := range c.Days {
+		date, err := time.ParseInLocation("2006-01-02", day.Date, Market)
+		if err != nil {
+			return fmt.Errorf("invalid calendar date %s: %v", day.Date, err)
+		}
+		open, err := time.ParseInLocation("2006-01-02 15:04", day.Date+" "+day.Open, Market)
+		if err != nil {
+			return fmt.Errorf("invalid open time %s on %s: %v", day.Open, day.Date, err)
+		}
+		close, err := time.ParseInLocation("2006-01-02 15:04", day.Date+" "+day.Close, Market)
+		if err != nil {
+			return fmt.Errorf("invalid close time %s on %s: %v", day.Close, day.Date, err)
+		}
+		c.sessions = append(c.sessions, Session{Date: date, Open: open, Close: close})
+	}
+	sort.Slice(c.sessions, func(i, j int) bool { return c.sessions[i].Date.Before(c.sessions[j].Date) })
+	return nil
+}
+
+// Save writes the calendar to path so it can be used without network access
+func (c *Calendar) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating calendar file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(c); err != nil {
+		return fmt.Errorf("error encoding calendar: %v", err)
+	}
+	return nil
+}
+
+// LoadCalendar reads the calendar cached at path and fetches it from the API if the
+// cache is missing or does not cover start to end (YYYY-MM-DD)
+func LoadCalendar(path, start, end string) (*Calendar, error) {
+	return defaultBroker.LoadCalendar(path, start, end)
+}
+
+func (b APIBroker) LoadCalendar(path, start, end string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err == nil {
+		var cached Calendar
+		err = json.NewDecoder(file).Decode(&cached)
+		file.Close()
+		if err == nil && cached.Start <= start && cached.End >= end {
+			if err := cached.parse(); err == nil {
+				return &cached, nil
+			}
+		}
+	}
+
+	days, err := b.GetCalendar(start, end)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewCalendar(start, end, days)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Save(path); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Calendar) Sessions() []Session {
+	return append([]Session(nil), c.sessions...)
+}
+
+// covers reports whether t falls within the fetched date range
+func (c *Calendar) covers(t time.Time) bool {
+	date := t.In(Market).Format("2006-01-02")
+	return date >= c.Start && date <= c.End
+}
+
+// sessionIndex returns the index of the first session on or after the market date of t
+func (c *Calendar) sessionIndex(t time.Time) int {
+	date := marketDate(t)
+	return sort.Search(len(c.sessions), func(i int) bool { return !c.sessions[i].Date.Before(date) })
+}
+
+func marketDate(t time.Time) time.Time {
+	y, m, d := t.In(Market).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, Market)
+}
+
+// Session returns the session held on the market date of t, if any
+func (c *Calendar) Session(t time.Time) (Session, bool) {
+	i := c.sessionIndex(t)
+	if i < len(c.sessions) && c.sessions[i].Date.Equal(marketDate(t)) {
+		return c.sessions[i], true
+	}
+	return Session{}, false
+}
+
+// IsOpen reports whether the regular session is open at t, which must be within the calendar
+func (c *Calendar) IsOpen(t time.Time) (bool, error) {
+	if !c.covers(t) {
+		return false, fmt.Errorf("%v is outside calendar %s to %s", t, c.Start, c.End)
+	}
+	s, ok := c.Session(t)
+	return ok && !t.Before(s.Open) && t.Before(s.Close), nil
+}
+
+// NextOpen returns the first session open strictly after t
+func (c *Calendar) NextOpen(t time.Time) (time.Time, error) {
+	for i := c.sessionIndex(t); i < len(c.sessions); i++ {
+		if c.sessions[i].Open.After(t) {
+			return c.sessions[i].Open, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no session after %v within calendar ending %s", t, c.End)
+}
+
+// NextClose returns the first session close strictly after t
+func (c *Calendar) NextClose(t time.Time) (time.Time, error) {
+	for i := c.sessionIndex(t); i < len(c.sessions); i++ {
+		if c.sessions[i].Close.After(t) {
+			return c.sessions[i].Close, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no session after %v within calendar ending %s", t, c.End)
+}
+
+// PreviousSession returns the last session held on a market date before the one of t
+func (c *Calendar) PreviousSession(t time.Time) (Session, error) {
+	i := c.sessionIndex(t)
+	if i == 0 || !c.covers(c.sessions[i-1].Date) {
+		return Session{}, fmt.Errorf("no session before %v within calendar starting %s", t, c.Start)
+	}
+	return c.sessions[i-1], nil
+}
+
+// TradingDaysBetween counts the sessions on market dates from the one of from (inclusive)
+// up to the one of to (exclusive)
+func (c *Calendar) TradingDaysBetween(from, to time.Time) (int, error) {
+	if !c.covers(from) || !c.covers(to) {
+		return 0, fmt.Errorf("range %v to %v exceeds calendar %s to %s", from, to, c.Start, c.End)
+	}
+	return c.sessionIndex(to) - c.sessionIndex(from), nil
+}
+
+// SessionsUntilExpiry counts the sessions left at t until the option expires, including the
+// current session if it has not closed yet and the session on the expiration date
+func (c *Calendar) SessionsUntilExpiry(o Option, t time.Time) (int, error) {
+	expiry, err := time.ParseInLocation("2006-01-02", o.ExpirationDate, Market)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiration date %s of %s", o.ExpirationDate, o.Symbol)
+	}
+	if !c.covers(t) || !c.covers(expiry) {
+		return 0, fmt.Errorf("expiration %s of %s exceeds calendar %s to %s", o.ExpirationDate, o.Symbol, c.Start, c.End)
+	}
+
+	from := c.sessionIndex(t)
+	if s, ok := c.Session(t); ok && !t.Before(s.Close) {
+		from++
+	}
+	n := c.sessionIndex(expiry.AddDate(0, 0, 1)) - from
+	if n < 0 {
+		n = 0
+	}
+	return n, nil
+}
@@ -0,0 +1,234 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendarDays returns the 2025 NYSE trading days from start to end, with the holidays left out
+// and the early closes at 13:00
+func calendarDays(start, end string) []CalendarDay {
+	holidays := map[string]bool{
+		"2025-01-01": true, "2025-01-09": true, "2025-01-20": true, "2025-02-17": true,
+		"2025-04-18": true, "2025-05-26": true, "2025-06-19": true, "2025-07-04": true,
+		"2025-09-01": true, "2025-11-27": true, "2025-12-25": true,
+	}
+	earlyCloses := map[string]bool{"2025-07-03": true, "2025-11-28": true, "2025-12-24": true}
+
+	var days []CalendarDay
+	first, _ := time.Parse("2006-01-02", start)
+	last, _ := time.Parse("2006-01-02", end)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || holidays[date] {
+			continue
+		}
+		day := CalendarDay{Date: date, Open: "09:30", Close: "16:00", SessionOpen: "0400", SessionClose: "2000"}
+		if earlyCloses[date] {
+			day.Close, day.SessionClose = "13:00", "1700"
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+func testCalendar(t *testing.T) *Calendar {
+	c, err := NewCalendar("2025-01-01", "2025-12-31", calendarDays("2025-01-01", "2025-12-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCalendarSessions(t *testing.T) {
+	c := testCalendar(t)
+	for _, tc := range []struct {
+		name        string
+		at          time.Time
+		open, close time.Time
+		early       bool
+	}{
+		// The open is 14:30 UTC under EST and 13:30 UTC under EDT
+		{"before DST", utc("2025-03-07T12:00:00Z"), utc("2025-03-07T14:30:00Z"), utc("2025-03-07T21:00:00Z"), false},
+		{"after DST start", utc("2025-03-10T12:00:00Z"), utc("2025-03-10T13:30:00Z"), utc("2025-03-10T20:00:00Z"), false},
+		{"before DST end", utc("2025-10-31T12:00:00Z"), utc("2025-10-31T13:30:00Z"), utc("2025-10-31T20:00:00Z"), false},
+		{"after DST end", utc("2025-11-03T12:00:00Z"), utc("2025-11-03T14:30:00Z"), utc("2025-11-03T21:00:00Z"), false},
+		{"early close under EDT", utc("2025-07-03T12:00:00Z"), utc("2025-07-03T13:30:00Z"), utc("2025-07-03T17:00:00Z"), true},
+		{"early close under EST", utc("2025-11-28T12:00:00Z"), utc("2025-11-28T14:30:00Z"), utc("2025-11-28T18:00:00Z"), true},
+		// 01:00 UTC is still the previous evening in New York
+		{"market date", utc("2025-03-11T01:00:00Z"), utc("2025-03-10T13:30:00Z"), utc("2025-03-10T20:00:00Z"), false},
+	} {
+		s, ok := c.Session(tc.at)
+		if !ok {
+			t.Errorf("%s: no session at %v", tc.name, tc.at)
+			continue
+		}
+		if !s.Open.Equal(tc.open) || !s.Close.Equal(tc.close) || s.EarlyClose() != tc.early {
+			t.Errorf("%s: session %v to %v early close %v, want %v to %v early close %v", tc.name, s.Open.UTC(), s.Close.UTC(), s.EarlyClose(), tc.open, tc.close, tc.early)
+		}
+	}
+
+	for _, at := range []time.Time{utc("2025-07-04T15:00:00Z"), utc("2025-03-08T15:00:00Z")} {
+		if s, ok := c.Session(at); ok {
+			t.Errorf("session %+v on the closed day of %v", s, at)
+		}
+	}
+}
+
+func TestCalendarIsOpen(t *testing.T) {
+	c := testCalendar(t)
+	for _, tc := range []struct {
+		at   string
+		open bool
+	}{
+		{"2025-03-07T14:29:59Z", false},
+		{"2025-03-07T14:30:00Z", true},
+		{"2025-03-07T20:59:59Z", true},
+		// The same UTC time is after the open once DST started
+		{"2025-03-10T13:45:00Z", true},
+		{"2025-03-07T13:45:00Z", false},
+		{"2025-03-10T20:00:00Z", false},
+		{"2025-11-03T13:45:00Z", false},
+		// Early closes end at 13:00 New York time
+		{"2025-07-03T16:59:59Z", true},
+		{"2025-07-03T17:00:00Z", false},
+		{"2025-11-28T17:59:59Z", true},
+		{"2025-11-28T18:00:00Z", false},
+		{"2025-07-04T15:00:00Z", false},
+		{"2025-03-08T15:00:00Z", false},
+	} {
+		open, err := c.IsOpen(utc(tc.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if open != tc.open {
+			t.Errorf("IsOpen(%s) = %v, want %v", tc.at, open, tc.open)
+		}
+	}
+
+	// Outside the fetched range the calendar does not know
+	for _, at := range []string{"2024-12-31T15:00:00Z", "2026-01-01T05:00:00Z"} {
+		if _, err := c.IsOpen(utc(at)); err == nil || !strings.Contains(err.Error(), "outside calendar") {
+			t.Errorf("IsOpen(%s) expected an outside calendar error, got %v", at, err)
+		}
+	}
+	// 2026-01-01 04:59:59 UTC is still New Year's Eve in New York
+	if open, err := c.IsOpen(utc("2026-01-01T04:59:59Z")); err != nil || open {
+		t.Errorf("IsOpen on New Year's Eve night = %v, %v, want closed", open, err)
+	}
+}
+
+func TestCalendarNavigation(t *testing.T) {
+	c := testCalendar(t)
+
+	// After the early close before Independence Day the next open is on Monday
+	next, err := c.NextOpen(utc("2025-07-03T17:30:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(utc("2025-07-07T13:30:00Z")) {
+		t.Errorf("next open %v, want 2025-07-07 09:30 EDT", next.UTC())
+	}
+	close, err := c.NextClose(utc("2025-07-03T15:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !close.Equal(utc("2025-07-03T17:00:00Z")) {
+		t.Errorf("next close %v, want the early close 2025-07-03 13:00 EDT", close.UTC())
+	}
+	if _, err := c.NextOpen(utc("2025-12-31T22:00:00Z")); err == nil {
+		t.Error("expected an error for an open after the calendar end")
+	}
+
+	prev, err := c.PreviousSession(utc("2025-07-07T15:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev.Date.Format("2006-01-02") != "2025-07-03" {
+		t.Errorf("previous session on %v, want 2025-07-03", prev.Date)
+	}
+	if _, err := c.PreviousSession(utc("2025-01-02T15:00:00Z")); err == nil {
+		t.Error("expected an error for a session before the calendar start")
+	}
+
+	// Monday to the next Monday over Independence Day on Friday
+	days, err := c.TradingDaysBetween(utc("2025-06-30T15:00:00Z"), utc("2025-07-07T15:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days != 4 {
+		t.Errorf("%d trading days, want 4", days)
+	}
+
+	expiring := Option{Symbol: "AAPL250711C00200000", ExpirationDate: "2025-07-11"}
+	for _, tc := range []struct {
+		at   string
+		want int
+	}{
+		{"2025-07-03T16:00:00Z", 6},
+		{"2025-07-03T17:00:00Z", 5},
+		{"2025-07-11T19:00:00Z", 1},
+		{"2025-07-11T20:00:00Z", 0},
+	} {
+		n, err := c.SessionsUntilExpiry(expiring, utc(tc.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tc.want {
+			t.Errorf("%d sessions until expiry at %s, want %d", n, tc.at, tc.want)
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/calendar" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		json.NewEncoder(w).Encode(calendarDays(r.URL.Query().Get("start"), r.URL.Query().Get("end")))
+	}))
+	defer srv.Close()
+	b := APIBroker{TradingURL: srv.URL, APIKeyID: "key", APISecretKey: "secret", Client: srv.Client()}
+	path := filepath.Join(t.TempDir(), "calendar.json")
+
+	load := func(start, end string) *Calendar {
+		c, err := b.LoadCalendar(path, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	load("2025-03-01", "2025-03-31")
+	c := load("2025-03-10", "2025-03-14")
+	if requests != 1 {
+		t.Errorf("%d requests, want the cached calendar for a range it covers", requests)
+	}
+	if open, err := c.IsOpen(utc("2025-03-10T13:45:00Z")); err != nil || !open {
+		t.Errorf("cached calendar IsOpen = %v, %v, want open", open, err)
+	}
+
+	// A range beyond the cached one is fetched
+	c = load("2025-03-01", "2025-04-30")
+	if requests != 2 || c.End != "2025-04-30" {
+		t.Errorf("%d requests and calendar ending %s, want a second request up to 2025-04-30", requests, c.End)
+	}
+	if _, ok := c.Session(utc("2025-04-18T15:00:00Z")); ok {
+		t.Error("session on Good Friday")
+	}
+}
@@ -0,0 +1,271 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Corporate action types accepted by CorporateActionsReq.Types
+const (
+	ActionForwardSplit       = "forward_split"
+	ActionReverseSplit       = "reverse_split"
+	ActionUnitSplit          = "unit_split"
+	ActionCashDividend       = "cash_dividend"
+	ActionStockDividend      = "stock_dividend"
+	ActionSpinOff            = "spin_off"
+	ActionCashMerger         = "cash_merger"
+	ActionStockMerger        = "stock_merger"
+	ActionStockAndCashMerger = "stock_and_cash_merger"
+)
+
+type CorporateActionsReq struct {
+	Symbols   []string
+	Types     []string
+	DateRange []string
+}
+
+// Split represents a forward or reverse stock split, new_rate shares for every old_rate shares
+type Split struct {
+	ID          string  `json:"id"`
+	Symbol      string  `json:"symbol"`
+	Cusip       string  `json:"cusip"`
+	OldCusip    string  `json:"old_cusip"`
+	NewCusip    string  `json:"new_cusip"`
+	NewRate     float64 `json:"new_rate"`
+	OldRate     float64 `json:"old_rate"`
+	ProcessDate string  `json:"process_date"`
+	ExDate      string  `json:"ex_date"`
+	RecordDate  string  `json:"record_date"`
+	PayableDate string  `json:"payable_date"`
+}
+
+// UnitSplit represents a split of one security into units of one or more other securities
+type UnitSplit struct {
+	ID              string  `json:"id"`
+	OldSymbol       string  `json:"old_symbol"`
+	OldCusip        string  `json:"old_cusip"`
+	OldRate         float64 `json:"old_rate"`
+	NewSymbol       string  `json:"new_symbol"`
+	NewCusip        string  `json:"new_cusip"`
+	NewRate         float64 `json:"new_rate"`
+	AlternateSymbol string  `json:"alternate_symbol"`
+	AlternateCusip  string  `json:"alternate_cusip"`
+	AlternateRate   float64 `json:"alternate_rate"`
+	ProcessDate     string  `json:"process_date"`
+	EffectiveDate   string  `json:"effective_date"`
+	PayableDate     string  `json:"payable_date"`
+}
+
+// Dividend represents a cash or a stock dividend
+type Dividend struct {
+	ID          string  `json:"id"`
+	Symbol      string  `json:"symbol"`
+	Cusip       string  `json:"cusip"`
+	Rate        float64 `json:"rate"`
+	Special     bool    `json:"special"`
+	Foreign     bool    `json:"foreign"`
+	ProcessDate string  `json:"process_date"`
+	ExDate      string  `json:"ex_date"`
+	RecordDate  string  `json:"record_date"`
+	PayableDate string  `json:"payable_date"`
+}
+
+// SpinOff represents new_rate shares of new_symbol distributed for every source_rate shares of source_symbol
+type SpinOff struct {
+	ID           string  `json:"id"`
+	SourceSymbol string  `json:"source_symbol"`
+	SourceCusip  string  `json:"source_cusip"`
+	SourceRate   float64 `json:"source_rate"`
+	NewSymbol    string  `json:"new_symbol"`
+	NewCusip     string  `json:"new_cusip"`
+	NewRate      float64 `json:"new_rate"`
+	ProcessDate  string  `json:"process_date"`
+	ExDate       string  `json:"ex_date"`
+	RecordDate   string  `json:"record_date"`
+	PayableDate  string  `json:"payable_date"`
+}
+
+// Merger represents a cash, stock or stock and cash merger
+type Merger struct {
+	ID             string  `json:"id"`
+	AcquirerSymbol string  `json:"acquirer_symbol"`
+	AcquirerCusip  string  `json:"acquirer_cusip"`
+	AcquirerRate   float64 `json:"acquirer_rate"`
+	AcquireeSymbol string  `json:"acquiree_symbol"`
+	AcquireeCusip  string  `json:"acquiree_cusip"`
+	AcquireeRate   float64 `json:"acquiree_rate"`
+	Rate           float64 `json:"rate"`
+	CashRate       float64 `json:"cash_rate"`
+	ProcessDate    string  `json:"process_date"`
+	EffectiveDate  string  `json:"effective_date"`
+	PayableDate    string  `json:"payable_date"`
+}
+
+type CorporateActions struct {
+	ForwardSplits       []Split     `json:"forward_splits"`
+	ReverseSplits       []Split     `json:"reverse_splits"`
+	UnitSplits          []UnitSplit `json:"unit_splits"`
+	CashDividends       []Dividend  `json:"cash_dividends"`
+	StockDividends      []Dividend  `json:"stock_dividends"`
+	SpinOffs            []SpinOff   `json:"spin_offs"`
+	CashMergers         []Merger    `json:"cash_mergers"`
+	StockMergers        []Merger    `json:"stock_mergers"`
+	StockAndCashMergers []Merger    `json:"stock_and_cash_mergers"`
+}
+
+func (a *CorporateActions) merge(page CorporateActions) {
+	a.ForwardSplits = append(a.ForwardSplits, page.ForwardSplits...)
+	a.ReverseSplits = append(a.ReverseSplits, page.ReverseSplits...)
+	a.UnitSplits = append(a.UnitSplits, page.UnitSplits...)
+	a.CashDividends = append(a.CashDividends, page.CashDividends...)
+	a.StockDividends = append(a.StockDividends, page.StockDividends...)
+	a.SpinOffs = append(a.SpinOffs, page.SpinOffs...)
+	a.CashMergers = append(a.CashMergers, page.CashMergers...)
+	a.StockMergers = append(a.StockMergers, page.StockMergers...)
+	a.StockAndCashMergers = append(a.StockAndCashMergers, page.StockAndCashMergers...)
+}
+
+// GetCorporateActions fetches all pages of corporate actions matching the request
+func GetCorporateActions(careq CorporateActionsReq) (CorporateActions, error) {
+	return defaultBroker.GetCorporateActions(careq)
+}
+
+func (b APIBroker) GetCorporateActions(careq CorporateActionsReq) (CorporateActions, error) {
+	var actions CorporateActions
+
+	if len(careq.DateRange) != 0 && len(careq.DateRange) != 2 {
+		return actions, fmt.Errorf("DateRange must contain a start and an end date")
+	}
+	for _, date := range careq.DateRange {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return actions, fmt.Errorf("invalid date format in DateRange: %s. Expected format: YYYY-MM-DD", date)
+		}
+	}
+
+	params := url.Values{}
+	params.Set("symbols", strings.Join(careq.Symbols, ","))
+	params.Set("types", strings.Join(careq.Types, ","))
+	if len(careq.DateRange) == 2 {
+		params.Set("start", careq.DateRange[0])
+		params.Set("end", careq.DateRange[1])
+	}
+	params.Set("limit", "1000")
+
+	for {
+		_, bodyStr, err := b.APIRequest(buildURL(b.dataURL(), "/v1/corporate-actions", params), 1)
+		if err != nil {
+			return actions, err
+		}
+
+		var page struct {
+			CorporateActions CorporateActions `json:"corporate_actions"`
+			NextPageToken    string           `json:"next_page_token"`
+		}
+		if err := json.Unmarshal([]byte(bodyStr), &page); err != nil {
+			return actions, fmt.Errorf("error parsing corporate actions: %v", err)
+		}
+		actions.merge(page.CorporateActions)
+
+		if page.NextPageToken == "" {
+			break
+		}
+		params.Set("page_token", page.NextPageToken)
+	}
+
+	return actions, nil
+}
+
+// HasStandardDeliverable reports whether exercising the contract delivers exactly Multiplier
+// shares of its underlying and nothing else. Contracts without deliverables are assumed standard.
+func (o Option) HasStandardDeliverable() bool {
+	if len(o.Deliverables) == 0 {
+		return true
+	}
+	if len(o.Deliverables) > 1 {
+		return false
+	}
+	d := o.Deliverables[0]
+	return d.Type == "equity" &&
+		d.Symbol == o.UnderlyingSymbol &&
+		d.Amount == float64(o.Multiplier) &&
+		(d.AllocationPercentage == 0 || d.AllocationPercentage == 100)
+}
+
+// SeparateNonStandard splits options into contracts with standard and non-standard deliverables
+func SeparateNonStandard(options []Option) ([]Option, []Option) {
+	var standard, nonStandard []Option
+	for _, opt := range options {
+		if opt.HasStandardDeliverable() {
+			standard = append(standard, opt)
+		} else {
+			nonStandard = append(nonStandard, opt)
+		}
+	}
+	return standard, nonStandard
+}
+
+// AdjustForSplits applies the splits in actions to option records fetched on date asOf (YYYY-MM-DD).
+// A split is applied when its ex-date lies after asOf and on or before the contract expiration.
+// Whole-number forward splits divide the strike, as the holder receives more contracts. Any other
+// split keeps the strike and changes the deliverable, which makes the contract non-standard.
+func AdjustForSplits(options []Option, actions CorporateActions, asOf string) ([]Option, error) {
+	if _, err := time.Parse("2006-01-02", asOf); err != nil {
+		return nil, fmt.Errorf("invalid date format for asOf: %s. Expected format: YYYY-MM-DD", asOf)
+	}
+
+	splits := append(append([]Split{}, actions.ForwardSplits...), actions.ReverseSplits...)
+
+	adjusted := make([]Option, len(options))
+	for i, opt := range options {
+		opt.Deliverables = append([]Deliverable(nil), opt.Deliverables...)
+
+		for _, split := range splits {
+			if split.Symbol != opt.UnderlyingSymbol || split.OldRate == 0 || split.NewRate == 0 {
+				continue
+			}
+			if split.ExDate <= asOf || split.ExDate > opt.ExpirationDate {
+				continue
+			}
+
+			ratio := split.NewRate / split.OldRate
+			if ratio > 1 && ratio == math.Trunc(ratio) {
+				opt.StrikePrice = math.Round(opt.StrikePrice/ratio*1000) / 1000
+				continue
+			}
+
+			if len(opt.Deliverables) == 0 {
+				opt.Deliverables = []Deliverable{{
+					Type:                 "equity",
+					Symbol:               opt.UnderlyingSymbol,
+					Amount:               float64(opt.Multiplier),
+					AllocationPercentage: 100,
+				}}
+			}
+			for j := range opt.Deliverables {
+				d := &opt.Deliverables[j]
+				if d.Type == "equity" && d.Symbol == opt.UnderlyingSymbol {
+					// Fractional shares are settled as cash in lieu and not delivered
+					d.Amount = math.Floor(d.Amount * ratio)
+				}
+			}
+		}
+
+		adjusted[i] = opt
+	}
+
+	return adjusted, nil
+}
+
+// String returns the split in new-for-old notation, e.g. "TSLA 3:1 (ex 2022-08-25)"
+func (s Split) String() string {
+	return fmt.Sprintf("%s %s:%s (ex %s)",
+		s.Symbol,
+		strconv.FormatFloat(s.NewRate, 'f', -1, 64),
+		strconv.FormatFloat(s.OldRate, 'f', -1, 64),
+		s.ExDate)
+}
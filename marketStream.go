@@ -0,0 +1,379 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// What a market data stream does when its consumer falls behind and the buffer is full
+const (
+	BackpressureBlock      = "block"       // stop reading, the server may disconnect a slow client
+	BackpressureDropOldest = "drop_oldest" // discard the oldest buffered event
+	BackpressureDropNewest = "drop_newest" // discard the incoming event
+)
+
+// StreamEvent is a market data message, e.g. *StreamTrade or *StreamQuote
+type StreamEvent interface {
+	EventSymbol() string
+}
+
+// marketStream is the connection handling shared by the stock and option data streams: it
+// authenticates, keeps the subscriptions across reconnects and buffers decoded events
+type marketStream struct {
+	// Capacity of the event buffer and the policy applied when it is full
+	Buffer       int
+	Backpressure string
+
+	// Keys to authenticate with, the package keys are used when empty
+	APIKeyID     string
+	APISecretKey string
+
+	url    string
+	header http.Header
+	binary bool // send binary frames, the encoding is not text
+	encode func(interface{}) ([]byte, error)
+	decode func([]byte) ([]map[string]interface{}, error)
+	parse  func(map[string]interface{}) StreamEvent
+
+	mutex   sync.Mutex
+	subs    map[string]map[string]bool
+	conn    *wsConn
+	events  chan StreamEvent
+	ran     bool
+	dropped uint64
+}
+
+func newMarketStream(url string, channels []string) *marketStream {
+	s := &marketStream{
+		Buffer:       10000,
+		Backpressure: BackpressureDropOldest,
+		url:          url,
+		header:       http.Header{},
+		subs:         make(map[string]map[string]bool),
+	}
+	for _, channel := range channels {
+		s.subs[channel] = make(map[string]bool)
+	}
+	return s
+}
+
+// Events returns the channel the decoded events are delivered on, closed when Run returns
+func (s *marketStream) Events() <-chan StreamEvent {
+	return s.eventChannel()
+}
+
+func (s *marketStream) eventChannel() chan StreamEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.events == nil {
+		s.events = make(chan StreamEvent, s.Buffer)
+	}
+	return s.events
+}
+
+// Dropped returns the number of events discarded because the consumer was too slow
+func (s *marketStream) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Subscriptions returns the subscribed symbols per channel
+func (s *marketStream) Subscriptions() map[string][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.subscriptionsLocked()
+}
+
+func (s *marketStream) subscriptionsLocked() map[string][]string {
+	subs := make(map[string][]string)
+	for channel, symbols := range s.subs {
+		for symbol := range symbols {
+			subs[channel] = append(subs[channel], symbol)
+		}
+		sort.Strings(subs[channel])
+	}
+	return subs
+}
+
+// update records a subscribe or unsubscribe and sends it if connected
+func (s *marketStream) update(action string, subs map[string][]string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message := map[string]interface{}{"action": action}
+	for channel, symbols := range subs {
+		if _, ok := s.subs[channel]; !ok {
+			return fmt.Errorf("unknown stream channel %s", channel)
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+		for _, symbol := range symbols {
+			if action == "subscribe" {
+				s.subs[channel][symbol] = true
+			} else {
+				delete(s.subs[channel], symbol)
+			}
+		}
+		message[channel] = symbols
+	}
+
+	if s.conn == nil || len(message) == 1 {
+		return nil
+	}
+	return s.send(s.conn, message)
+}
+
+func (s *marketStream) send(conn *wsConn, message interface{}) error {
+	data, err := s.encode(message)
+	if err != nil {
+		return fmt.Errorf("error encoding stream message: %v", err)
+	}
+	if s.binary {
+		return conn.WriteBinary(data)
+	}
+	return conn.WriteMessage(data)
+}
+
+// read returns the next batch of decoded messages
+func (s *marketStream) read(conn *wsConn) ([]map[string]interface{}, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := s.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding stream message: %v", err)
+	}
+	return msgs, nil
+}
+
+// streamError is an error message of the stream, codes 402 to 409 are not retried
+type streamError struct {
+	Code int
+	Msg  string
+}
+
+func (e streamError) Error() string {
+	return fmt.Sprintf("stream error %d: %s", e.Code, e.Msg)
+}
+
+func (e streamError) fatal() bool {
+	return e.Code >= 402 && e.Code <= 409
+}
+
+// awaitControl reads until a control message of type "success" with text msg arrives
+func (s *marketStream) awaitControl(conn *wsConn, msg string) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		msgs, err := s.read(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			switch getString(m["T"]) {
+			case "error":
+				return streamError{Code: getInt(m["code"]), Msg: getString(m["msg"])}
+			case "success":
+				if getString(m["msg"]) == msg {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (s *marketStream) connect() (*wsConn, error) {
+	keyID, secretKey := APIBroker{APIKeyID: s.APIKeyID, APISecretKey: s.APISecretKey}.keys()
+	if keyID == "" || secretKey == "" {
+		return nil, fmt.Errorf("APIKeyID or APISecretKey is not set")
+	}
+
+	conn, err := dialWebSocket(s.url, 10*time.Second, s.header)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.awaitControl(conn, "connected"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := s.send(conn, map[string]string{"action": "auth", "key": keyID, "secret": secretKey}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := s.awaitControl(conn, "authenticated"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Restore the subscriptions, also after a reconnect
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	message := map[string]interface{}{"action": "subscribe"}
+	for channel, symbols := range s.subscriptionsLocked() {
+		message[channel] = symbols
+	}
+	if len(message) > 1 {
+		if err := s.send(conn, message); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// Run connects and delivers events on Events until ctx is done, reconnecting with backoff and
+// resubscribing after connection loss. Authentication and subscription limit errors end Run.
+// A stream runs once, as Events is closed afterwards, so create a new stream to run again.
+func (s *marketStream) Run(ctx context.Context) error {
+	events := s.eventChannel()
+	s.mutex.Lock()
+	ran := s.ran
+	s.ran = true
+	s.mutex.Unlock()
+	if ran {
+		return fmt.Errorf("market data stream already ran, create a new stream")
+	}
+	defer close(events)
+
+	attempt := 0
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		conn, err := s.connect()
+		if serr, ok := err.(streamError); ok && serr.fatal() {
+			return err
+		}
+		if err != nil {
+			wait := backoff(attempt)
+			fmt.Printf("Market data stream connection failed: %v, retrying in %v\n", err, wait)
+			attempt++
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		attempt = 0
+
+		err = s.listen(ctx, conn, events)
+		s.mutex.Lock()
+		s.conn = nil
+		s.mutex.Unlock()
+		conn.Close()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if serr, ok := err.(streamError); ok && serr.fatal() {
+			return err
+		}
+		fmt.Printf("Market data stream disconnected: %v, reconnecting\n", err)
+	}
+}
+
+func (s *marketStream) listen(ctx context.Context, conn *wsConn, events chan StreamEvent) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		msgs, err := s.read(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			switch getString(m["T"]) {
+			case "success", "subscription":
+				continue
+			case "error":
+				serr := streamError{Code: getInt(m["code"]), Msg: getString(m["msg"])}
+				if serr.fatal() {
+					return serr
+				}
+				fmt.Printf("Market data stream: %v\n", serr)
+				continue
+			}
+
+			if event := s.parse(m); event != nil {
+				if !s.enqueue(ctx, events, event) {
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+// enqueue hands an event to the consumer according to the backpressure policy
+func (s *marketStream) enqueue(ctx context.Context, events chan StreamEvent, event StreamEvent) bool {
+	switch s.Backpressure {
+	case BackpressureDropNewest:
+		select {
+		case events <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	case BackpressureDropOldest:
+		for {
+			select {
+			case events <- event:
+				return true
+			default:
+			}
+			select {
+			case <-events:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// getTime reads a timestamp sent as RFC 3339 string (JSON) or as time (MessagePack)
+func getTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case string:
+		parsed, _ := time.Parse(time.RFC3339Nano, t)
+		return parsed
+	}
+	return time.Time{}
+}
+
+// getStrings reads an array of strings, a single string is returned as one element
+func getStrings(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []interface{}:
+		var strs []string
+		for _, item := range s {
+			strs = append(strs, getString(item))
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// marketSession answers the connect and auth messages of a market data stream and returns the
+// subscribe message that follows when the stream has subscriptions
+func marketSession(p *wsPeer, subscribed bool) map[string][]string {
+	p.writeText(`[{"T":"success","msg":"connected"}]`)
+	if _, payload := p.read(); !strings.Contains(string(payload), `"auth"`) {
+		p.t.Errorf("first message %s, want the authorization", payload)
+	}
+	p.writeText(`[{"T":"success","msg":"authenticated"}]`)
+	if !subscribed {
+		return nil
+	}
+	return readSubscription(p)
+}
+
+// readSubscription reads a subscribe message and returns its symbols per channel
+func readSubscription(p *wsPeer) map[string][]string {
+	_, payload := p.read()
+	var message map[string]interface{}
+	if err := json.Unmarshal(payload, &message); err != nil || message["action"] != "subscribe" {
+		p.t.Errorf("message %s, want a subscription", payload)
+		return nil
+	}
+	subs := make(map[string][]string)
+	for channel, symbols := range message {
+		if channel != "action" {
+			subs[channel] = getStrings(symbols)
+		}
+	}
+	return subs
+}
+
+func trades(prices ...int) string {
+	var msgs []string
+	for _, price := range prices {
+		msgs = append(msgs, fmt.Sprintf(`{"T":"t","S":"AAPL","p":%d,"s":1,"t":"2025-01-10T15:00:00Z"}`, price))
+	}
+	return "[" + strings.Join(msgs, ",") + "]"
+}
+
+func newTestStockStream(url string) *StockStream {
+	s := NewStockStream("iex")
+	s.url = url
+	s.APIKeyID, s.APISecretKey = "key", "secret"
+	return s
+}
+
+func receivedPrices(t *testing.T, events <-chan StreamEvent, n int) []int {
+	var prices []int
+	for i := 0; i < n; i++ {
+		select {
+		case event := <-events:
+			prices = append(prices, int(event.(*StreamTrade).Price))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want %d events", prices, n)
+		}
+	}
+	return prices
+}
+
+func TestMarketStreamBackpressure(t *testing.T) {
+	for _, tc := range []struct {
+		policy  string
+		want    []int
+		dropped uint64
+	}{
+		{BackpressureBlock, []int{1, 2, 3, 4, 5}, 0},
+		{BackpressureDropOldest, []int{4, 5}, 3},
+		{BackpressureDropNewest, []int{1, 2}, 3},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			sent := make(chan struct{})
+			srv := wsServer(t, time.Time{}, func(p *wsPeer) {
+				marketSession(p, true)
+				p.writeText(trades(1, 2, 3, 4, 5))
+				close(sent)
+				p.conn.SetReadDeadline(time.Time{})
+				p.reader.ReadByte()
+			})
+			defer srv.Close()
+
+			s := newTestStockStream(wsStreamURL(srv))
+			s.Buffer = 2
+			s.Backpressure = tc.policy
+			s.Subscribe(StockSubscription{Trades: []string{"AAPL"}})
+			events := s.Events()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error)
+			go func() { done <- s.Run(ctx) }()
+
+			// The consumer only starts reading once the stream handled all five trades
+			<-sent
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) && (len(events) < 2 || s.Dropped() < tc.dropped) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if len(events) != 2 {
+				t.Errorf("%d buffered events, want a full buffer of 2", len(events))
+			}
+
+			if got := receivedPrices(t, events, len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("received trades %v, want %v", got, tc.want)
+			}
+			if s.Dropped() != tc.dropped {
+				t.Errorf("dropped %d events, want %d", s.Dropped(), tc.dropped)
+			}
+
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("Run returned %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestMarketStreamResubscribe(t *testing.T) {
+	var sessions int32
+	subscribed := make(chan map[string][]string, 3)
+	added := make(chan bool)
+	srv := wsServer(t, time.Time{}, func(p *wsPeer) {
+		subs := marketSession(p, true)
+		subscribed <- subs
+		if atomic.AddInt32(&sessions, 1) == 1 {
+			// A subscription added while connected is sent right away, then the connection drops
+			<-added
+			subscribed <- readSubscription(p)
+			p.write(true, wsClose, []byte{0x03, 0xE9})
+			p.read()
+			return
+		}
+		p.writeText(trades(1))
+		p.conn.SetReadDeadline(time.Time{})
+		p.reader.ReadByte()
+	})
+	defer srv.Close()
+
+	s := newTestStockStream(wsStreamURL(srv))
+	s.Subscribe(StockSubscription{Trades: []string{"AAPL"}})
+	events := s.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	receive := func() map[string][]string {
+		select {
+		case subs := <-subscribed:
+			return subs
+		case <-time.After(5 * time.Second):
+			t.Fatal("no subscription received")
+		}
+		return nil
+	}
+	if subs := receive(); !reflect.DeepEqual(subs, map[string][]string{ChannelTrades: {"AAPL"}}) {
+		t.Errorf("first connection subscribed %v, want AAPL trades", subs)
+	}
+	if err := s.Subscribe(StockSubscription{Quotes: []string{"MSFT"}}); err != nil {
+		t.Fatal(err)
+	}
+	added <- true
+	if subs := receive(); !reflect.DeepEqual(subs, map[string][]string{ChannelQuotes: {"MSFT"}}) {
+		t.Errorf("live subscription %v, want MSFT quotes", subs)
+	}
+	want := map[string][]string{ChannelTrades: {"AAPL"}, ChannelQuotes: {"MSFT"}}
+	if subs := receive(); !reflect.DeepEqual(subs, want) {
+		t.Errorf("reconnect subscribed %v, want %v", subs, want)
+	}
+	if got := receivedPrices(t, events, 1); got[0] != 1 {
+		t.Errorf("received trade %v after the reconnect, want 1", got)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+
+	// The events channel is closed and the stream can't run again
+	if _, ok := <-events; ok {
+		t.Error("events channel still open after Run returned")
+	}
+	if err := s.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "already ran") {
+		t.Errorf("expected the second Run to fail, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package alpacaApiClient
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An options stream frame with a trade and a quote, laid out like the frames of the v1beta1
+// stream: an array of maps with fixstr keys, float64 prices, unsigned sizes and timestamp
+// extensions (-1) in the 12 and 8 byte forms.
+var optionFrame = strings.Join([]string{
+	"92",
+	// {"T": "t", "S": "AAPL250117C00200000", "t": ts12, "p": 2.63, "s": 5, "x": "C", "c": "I"}
+	"87a154a174a153b34141504c323530313137433030323030303030",
+	"a174c70cff075bcd150000000067658765",
+	"a170cb40050a3d70a3d70aa17305a178a143a163a149",
+	// {"T": "q", "S": "AAPL250117C00200000", "t": ts8, "bx": "W", "bp": 2.6, "bs": 120,
+	//  "ax": "C", "ap": 2.67, "as": 300, "c": "A"}
+	"8aa154a171a153b34141504c323530313137433030323030303030",
+	"a174d7ff1d6f345467658765",
+	"a26278a157a26270cb4004cccccccccccda2627378",
+	"a26178a143a26170cb40055c28f5c28f5ca26173cd012ca163a141",
+}, "")
+
+func TestDecodeOptionFrame(t *testing.T) {
+	data, err := hex.DecodeString(optionFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := decodeMsgpackMessages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("decoded %d messages, want 2", len(msgs))
+	}
+	timestamp := time.Date(2024, 12, 20, 15, 4, 5, 123456789, time.UTC)
+
+	trade, ok := parseOptionMessage(msgs[0]).(*OptionTrade)
+	if !ok {
+		t.Fatalf("first message parsed as %T, want *OptionTrade", parseOptionMessage(msgs[0]))
+	}
+	wantTrade := OptionTrade{
+		Symbol: "AAPL250117C00200000",
+		Trade:  Trade{Condition: "I", Price: 2.63, Size: 5, Timestamp: timestamp, Exchange: "C"},
+	}
+	if *trade != wantTrade {
+		t.Errorf("trade %+v, want %+v", *trade, wantTrade)
+	}
+
+	quote, ok := parseOptionMessage(msgs[1]).(*OptionQuote)
+	if !ok {
+		t.Fatalf("second message parsed as %T, want *OptionQuote", parseOptionMessage(msgs[1]))
+	}
+	wantQuote := OptionQuote{
+		Symbol: "AAPL250117C00200000",
+		Quote: Quote{
+			AskPrice: 2.67, AskSize: 300, AskExchange: "C",
+			BidPrice: 2.6, BidSize: 120, BidExchange: "W",
+			Condition: "A", Timestamp: timestamp,
+		},
+	}
+	if *quote != wantQuote {
+		t.Errorf("quote %+v, want %+v", *quote, wantQuote)
+	}
+}
+
+func TestDecodeTruncatedFrame(t *testing.T) {
+	data, _ := hex.DecodeString(optionFrame)
+	for _, n := range []int{1, 30, 60, len(data) - 1} {
+		if _, err := decodeMsgpackMessages(data[:n]); err == nil {
+			t.Errorf("expected an error for a frame cut to %d bytes", n)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	action := map[string]interface{}{"action": "subscribe", "quotes": []string{"AAPL250117C00200000"}, "limit": 1000}
+	data, err := msgpackMarshal(action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := msgpackUnmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		t.Fatalf("decoded %T, want a map", v)
+	}
+	quotes, _ := m["quotes"].([]interface{})
+	if m["action"] != "subscribe" || len(quotes) != 1 || quotes[0] != "AAPL250117C00200000" || m["limit"] != float64(1000) {
+		t.Errorf("decoded %v", m)
+	}
+}
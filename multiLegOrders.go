@@ -0,0 +1,170 @@
+package alpacaApiClient
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxLegs is the largest number of legs a multi-leg option order may have
+const MaxLegs = 4
+
+// mlegPayload is one leg of a multi-leg order body
+type mlegPayload struct {
+	Symbol         string  `json:"symbol"`
+	RatioQty       Decimal `json:"ratio_qty"`
+	Side           string  `json:"side"`
+	PositionIntent string  `json:"position_intent,omitempty"`
+}
+
+type OrderLeg struct {
+	Option         Option
+	Ratio          int
+	Side           string
+	PositionIntent string
+}
+
+// MultiLegOrder builds an mleg option order such as a vertical, straddle or iron condor.
+// Qty is the number of spreads, each leg trades Qty times its Ratio.
+type MultiLegOrder struct {
+	Qty           int
+	TimeInForce   string
+	ClientOrderID string
+	Legs          []OrderLeg
+}
+
+func NewMultiLegOrder(qty int) *MultiLegOrder {
+	return &MultiLegOrder{Qty: qty, TimeInForce: TimeInForceDay}
+}
+
+// AddLeg appends a leg and returns the order for chaining
+func (m *MultiLegOrder) AddLeg(o Option, ratio int, side string, intent string) *MultiLegOrder {
+	m.Legs = append(m.Legs, OrderLeg{Option: o, Ratio: ratio, Side: side, PositionIntent: intent})
+	return m
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Validate checks the leg count, that all legs share one underlying and that the leg
+// ratios are positive and in their simplest form, e.g. 1:2 rather than 2:4
+func (m *MultiLegOrder) Validate() error {
+	if m.Qty <= 0 {
+		return fmt.Errorf("multi-leg orders require a positive whole quantity")
+	}
+	if len(m.Legs) < 2 || len(m.Legs) > MaxLegs {
+		return fmt.Errorf("multi-leg orders require 2 to %d legs, got %d", MaxLegs, len(m.Legs))
+	}
+	if m.TimeInForce != TimeInForceDay && m.TimeInForce != TimeInForceGTC {
+		return fmt.Errorf("multi-leg orders require time in force day or gtc")
+	}
+
+	underlying := m.Legs[0].Option.UnderlyingSymbol
+	symbols := make(map[string]bool)
+	divisor := 0
+	for _, leg := range m.Legs {
+		if leg.Option.UnderlyingSymbol != underlying {
+			return fmt.Errorf("leg %s has underlying %s, expected %s", leg.Option.Symbol, leg.Option.UnderlyingSymbol, underlying)
+		}
+		if symbols[leg.Option.Symbol] {
+			return fmt.Errorf("contract %s appears in more than one leg", leg.Option.Symbol)
+		}
+		symbols[leg.Option.Symbol] = true
+
+		if leg.Ratio <= 0 {
+			return fmt.Errorf("leg %s requires a positive ratio", leg.Option.Symbol)
+		}
+		divisor = gcd(divisor, leg.Ratio)
+
+		if leg.Side != SideBuy && leg.Side != SideSell {
+			return fmt.Errorf("invalid side %q of leg %s", leg.Side, leg.Option.Symbol)
+		}
+		if err := validateIntent(leg.Side, leg.PositionIntent); err != nil {
+			return fmt.Errorf("leg %s: %v", leg.Option.Symbol, err)
+		}
+	}
+	if divisor != 1 {
+		return fmt.Errorf("leg ratios must be in simplest form, divide them by %d and raise Qty instead", divisor)
+	}
+
+	return nil
+}
+
+// NetPrice returns the net price of one spread, positive for a debit and negative for a credit.
+// PriceMid nets the leg mids, PriceAsk is the natural price (buying at the ask and selling at
+// the bid) and PriceBid the opposite. Policy ticks move the net price in steps of 0.01.
+func (m *MultiLegOrder) NetPrice(policy LimitPolicy) (float64, error) {
+	net := 0.0
+	for _, leg := range m.Legs {
+		q := leg.Option.LatestQuote
+		if q == nil || q.AskPrice <= 0 {
+			return 0, fmt.Errorf("no quote available for %s", leg.Option.Symbol)
+		}
+
+		buy := leg.Side == SideBuy
+		var price float64
+		switch policy.Reference {
+		case PriceMid:
+			price = (q.BidPrice + q.AskPrice) / 2
+		case PriceAsk:
+			price = q.BidPrice
+			if buy {
+				price = q.AskPrice
+			}
+		case PriceBid:
+			price = q.AskPrice
+			if buy {
+				price = q.BidPrice
+			}
+		default:
+			return 0, fmt.Errorf("invalid limit policy reference %q", policy.Reference)
+		}
+
+		if buy {
+			net += price * float64(leg.Ratio)
+		} else {
+			net -= price * float64(leg.Ratio)
+		}
+	}
+
+	net += float64(policy.Ticks) * 0.01
+	return math.Round(net*100) / 100, nil
+}
+
+func (m *MultiLegOrder) payload(limitPrice float64) orderPayload {
+	p := orderPayload{
+		Qty:           Decimal(m.Qty),
+		Type:          OrderTypeLimit,
+		TimeInForce:   m.TimeInForce,
+		LimitPrice:    Decimal(limitPrice),
+		ClientOrderID: m.ClientOrderID,
+		OrderClass:    OrderClassMLeg,
+	}
+	for _, leg := range m.Legs {
+		p.Legs = append(p.Legs, mlegPayload{
+			Symbol:         leg.Option.Symbol,
+			RatioQty:       Decimal(leg.Ratio),
+			Side:           leg.Side,
+			PositionIntent: leg.PositionIntent,
+		})
+	}
+	return p
+}
+
+// Submit validates the order and places it as one limit order priced by policy
+func (m *MultiLegOrder) Submit(policy LimitPolicy) (Order, error) {
+	if err := m.Validate(); err != nil {
+		return Order{}, err
+	}
+	limitPrice, err := m.NetPrice(policy)
+	if err != nil {
+		return Order{}, err
+	}
+	if limitPrice == 0 {
+		return Order{}, fmt.Errorf("net price of the spread is zero")
+	}
+	return defaultBroker.postOrder(m.payload(limitPrice))
+}
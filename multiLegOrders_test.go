@@ -0,0 +1,112 @@
+package alpacaApiClient
+
+import (
+	"strings"
+	"testing"
+)
+
+func legOption(symbol string, bid, ask float64) Option {
+	return Option{Symbol: symbol, UnderlyingSymbol: "AAPL", RootSymbol: "AAPL", LatestQuote: &Quote{BidPrice: bid, AskPrice: ask}}
+}
+
+var (
+	call100 = legOption("AAPL250117C00100000", 5.00, 5.20)
+	call105 = legOption("AAPL250117C00105000", 2.00, 2.20)
+	call110 = legOption("AAPL250117C00110000", 0.80, 0.90)
+)
+
+func TestMultiLegOrderValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		ratios []int
+		err    string
+	}{
+		{"1:1", []int{1, 1}, ""},
+		{"1:2", []int{1, 2}, ""},
+		{"2:3", []int{2, 3}, ""},
+		{"1:2:1", []int{1, 2, 1}, ""},
+		{"2:4", []int{2, 4}, "divide them by 2"},
+		{"3:3", []int{3, 3}, "divide them by 3"},
+		{"2:4:2", []int{2, 4, 2}, "divide them by 2"},
+		{"6:9:3", []int{6, 9, 3}, "divide them by 3"},
+		{"zero", []int{1, 0}, "positive ratio"},
+		{"negative", []int{-1, 1}, "positive ratio"},
+	}
+	options := []Option{call100, call105, call110}
+	sides := []string{SideBuy, SideSell, SideBuy}
+	for _, test := range tests {
+		order := NewMultiLegOrder(1)
+		for i, ratio := range test.ratios {
+			order.AddLeg(options[i], ratio, sides[i], "")
+		}
+		err := order.Validate()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case test.err != "" && err == nil:
+			t.Errorf("%s: expected an error containing %q", test.name, test.err)
+		case test.err != "" && !strings.Contains(err.Error(), test.err):
+			t.Errorf("%s: error %q, want one containing %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestMultiLegOrderValidateLegs(t *testing.T) {
+	other := legOption("MSFT250117C00400000", 3.00, 3.20)
+	other.UnderlyingSymbol = "MSFT"
+
+	tests := []struct {
+		name  string
+		order *MultiLegOrder
+	}{
+		{"one leg", NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "")},
+		{"zero qty", NewMultiLegOrder(0).AddLeg(call100, 1, SideBuy, "").AddLeg(call105, 1, SideSell, "")},
+		{"underlyings", NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "").AddLeg(other, 1, SideSell, "")},
+		{"same contract", NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "").AddLeg(call100, 1, SideSell, "")},
+		{"intent", NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, PositionIntentSellToOpen).AddLeg(call105, 1, SideSell, "")},
+	}
+	for _, test := range tests {
+		if err := test.order.Validate(); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestMultiLegOrderNetPrice(t *testing.T) {
+	vertical := NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "").AddLeg(call105, 1, SideSell, "")
+	credit := NewMultiLegOrder(1).AddLeg(call100, 1, SideSell, "").AddLeg(call105, 1, SideBuy, "")
+	ratio := NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "").AddLeg(call105, 2, SideSell, "")
+
+	tests := []struct {
+		name   string
+		order  *MultiLegOrder
+		policy LimitPolicy
+		want   float64
+	}{
+		{"vertical mid", vertical, AtMid, 3.00},
+		{"vertical natural", vertical, AtAsk, 3.20},
+		{"vertical bid", vertical, AtBid, 2.80},
+		{"vertical mid minus ticks", vertical, MidPlusTicks(-2), 2.98},
+		{"credit mid", credit, AtMid, -3.00},
+		{"credit natural", credit, AtAsk, -2.80},
+		{"ratio mid", ratio, AtMid, 0.90},
+	}
+	for _, test := range tests {
+		price, err := test.order.NetPrice(test.policy)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if price != test.want {
+			t.Errorf("%s: net price %v, want %v", test.name, price, test.want)
+		}
+	}
+
+	unquoted := NewMultiLegOrder(1).AddLeg(call100, 1, SideBuy, "").AddLeg(Option{Symbol: "AAPL250117C00120000"}, 1, SideSell, "")
+	if _, err := unquoted.NetPrice(AtMid); err == nil {
+		t.Error("expected an error for a leg without quote")
+	}
+	if _, err := vertical.NetPrice(LimitPolicy{Reference: "last"}); err == nil {
+		t.Error("expected an error for an invalid reference")
+	}
+}
@@ -0,0 +1,269 @@
+package alpacaApiClient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OptionSnapshot is an option chain as captured at one time
+type OptionSnapshot struct {
+	CapturedAt time.Time `json:"captured_at"`
+	Underlying string    `json:"underlying"`
+	Options    []Option  `json:"options"`
+}
+
+// ContractSnapshot is one contract of an OptionSnapshot
+type ContractSnapshot struct {
+	CapturedAt time.Time
+	Option     Option
+}
+
+// OptionArchive is an append-only store of chain snapshots. Each underlying has a directory
+// with a JSON Lines file per New York date, one snapshot per line:
+//
+//	archive/AAPL/2025-01-17.jsonl
+//
+// A line cut short by a crash during Append is ignored and removed by the next Append.
+type OptionArchive struct {
+	Dir string
+
+	mutex sync.Mutex
+}
+
+func OpenOptionArchive(dir string) (*OptionArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating option archive: %v", err)
+	}
+	return &OptionArchive{Dir: dir}, nil
+}
+
+// Capture fetches options from source, e.g. APIBroker, and appends them captured at the time
+// of the request
+func (a *OptionArchive) Capture(source OptionChainSource, optreq OptionURLReq, nMax int) ([]Option, error) {
+	capturedAt := time.Now().UTC()
+	options, _, err := source.GetOptions(optreq, nMax)
+	if err != nil {
+		return nil, err
+	}
+	return options, a.Append(capturedAt, options)
+}
+
+// Append stores options captured at capturedAt as one snapshot per underlying
+func (a *OptionArchive) Append(capturedAt time.Time, options []Option) error {
+	byUnderlying := make(map[string][]Option)
+	var underlyings []string
+	for _, o := range options {
+		if _, ok := byUnderlying[o.UnderlyingSymbol]; !ok {
+			underlyings = append(underlyings, o.UnderlyingSymbol)
+		}
+		byUnderlying[o.UnderlyingSymbol] = append(byUnderlying[o.UnderlyingSymbol], o)
+	}
+	sort.Strings(underlyings)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, underlying := range underlyings {
+		dir, err := a.underlyingDir(underlying)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating option archive: %v", err)
+		}
+
+		line, err := json.Marshal(OptionSnapshot{CapturedAt: capturedAt, Underlying: underlying, Options: byUnderlying[underlying]})
+		if err != nil {
+			return fmt.Errorf("error encoding snapshot: %v", err)
+		}
+		path := filepath.Join(dir, capturedAt.In(Market).Format("2006-01-02")+".jsonl")
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return fmt.Errorf("error opening archive file: %v", err)
+		}
+		err = truncateTornLine(file)
+		if err == nil {
+			_, err = file.Write(append(line, '\n'))
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("error appending snapshot: %v", err)
+		}
+	}
+	return nil
+}
+
+// truncateTornLine removes a last line without newline left by an interrupted append
+func truncateTornLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	end := info.Size()
+	buf := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		n := int64(len(buf))
+		if n > offset {
+			n = offset
+		}
+		offset -= n
+		if _, err := file.ReadAt(buf[:n], offset); err != nil {
+			return err
+		}
+		for i := n - 1; i >= 0; i-- {
+			if buf[i] == '\n' {
+				if offset+i+1 == end {
+					return nil
+				}
+				return file.Truncate(offset + i + 1)
+			}
+		}
+	}
+	return file.Truncate(0)
+}
+
+func (a *OptionArchive) underlyingDir(underlying string) (string, error) {
+	if underlying == "" || strings.ContainsAny(underlying, `/\`) || strings.HasPrefix(underlying, ".") {
+		return "", fmt.Errorf("invalid underlying symbol %q", underlying)
+	}
+	return filepath.Join(a.Dir, underlying), nil
+}
+
+// Snapshots returns the snapshots of an underlying captured from from to to inclusive, oldest
+// first. A zero from or to leaves that end open.
+func (a *OptionArchive) Snapshots(underlying string, from, to time.Time) ([]OptionSnapshot, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	files, err := a.files(underlying, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []OptionSnapshot
+	for _, path := range files {
+		err := readSnapshots(path, func(s OptionSnapshot) {
+			if (from.IsZero() || !s.CapturedAt.Before(from)) && (to.IsZero() || !s.CapturedAt.After(to)) {
+				snapshots = append(snapshots, s)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CapturedAt.Before(snapshots[j].CapturedAt) })
+	return snapshots, nil
+}
+
+// History returns the snapshots of one contract captured from from to to inclusive, oldest first
+func (a *OptionArchive) History(underlying string, symbol string, from, to time.Time) ([]ContractSnapshot, error) {
+	snapshots, err := a.Snapshots(underlying, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var history []ContractSnapshot
+	for _, s := range snapshots {
+		for _, o := range s.Options {
+			if o.Symbol == symbol {
+				history = append(history, ContractSnapshot{CapturedAt: s.CapturedAt, Option: o})
+				break
+			}
+		}
+	}
+	return history, nil
+}
+
+// ChainAsOf returns the latest snapshot of an underlying captured at or before t
+func (a *OptionArchive) ChainAsOf(underlying string, t time.Time) (OptionSnapshot, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	files, err := a.files(underlying, time.Time{}, t)
+	if err != nil {
+		return OptionSnapshot{}, err
+	}
+
+	// Newest file first, the first file with a match holds the latest snapshot
+	for i := len(files) - 1; i >= 0; i-- {
+		var latest *OptionSnapshot
+		err := readSnapshots(files[i], func(s OptionSnapshot) {
+			if !s.CapturedAt.After(t) && (latest == nil || !s.CapturedAt.Before(latest.CapturedAt)) {
+				latest = &s
+			}
+		})
+		if err != nil {
+			return OptionSnapshot{}, err
+		}
+		if latest != nil {
+			return *latest, nil
+		}
+	}
+	return OptionSnapshot{}, fmt.Errorf("no %s snapshot captured at or before %s", underlying, t.Format(time.RFC3339))
+}
+
+// files returns the date files of an underlying that may hold snapshots from from to to, sorted
+func (a *OptionArchive) files(underlying string, from, to time.Time) ([]string, error) {
+	dir, err := a.underlyingDir(underlying)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading option archive: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		date := strings.TrimSuffix(entry.Name(), ".jsonl")
+		if entry.IsDir() || date == entry.Name() {
+			continue
+		}
+		if !from.IsZero() && date < from.In(Market).Format("2006-01-02") {
+			continue
+		}
+		if !to.IsZero() && date > to.In(Market).Format("2006-01-02") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readSnapshots calls fn with each snapshot of a file
+func readSnapshots(path string, fn func(OptionSnapshot)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening archive file: %v", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// A last line without newline is an interrupted append
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive file: %v", err)
+		}
+		var s OptionSnapshot
+		if err := json.Unmarshal(line, &s); err != nil {
+			return fmt.Errorf("error parsing %s line %d: %v", path, n, err)
+		}
+		fn(s)
+	}
+}
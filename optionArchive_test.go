@@ -0,0 +1,204 @@
+package alpacaApiClient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveChain returns the export options with bid as the bid of the call and an MSFT contract
+func archiveChain(bid float64) []Option {
+	options := exportOptions()
+	quote := *options[0].LatestQuote
+	quote.BidPrice = bid
+	options[0].LatestQuote = &quote
+	msft := Option{Symbol: "MSFT250117C00420000", UnderlyingSymbol: "MSFT", Type: "call", StrikePrice: 420, Multiplier: 100}
+	return append(options, msft)
+}
+
+type staticChain []Option
+
+func (c staticChain) GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
+	return c, "", nil
+}
+
+func readArchiveFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOptionArchive(t *testing.T) {
+	archive, err := OpenOptionArchive(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second capture is after midnight UTC but on the same New York date as the first
+	times := []time.Time{
+		time.Date(2025, 1, 10, 15, 0, 0, 123456789, time.UTC),
+		time.Date(2025, 1, 11, 3, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 13, 15, 0, 0, 0, time.UTC),
+	}
+	aaplFile := filepath.Join(archive.Dir, "AAPL", "2025-01-10.jsonl")
+
+	var written string
+	for i, at := range times {
+		if err := archive.Append(at, archiveChain(float64(i+1))); err != nil {
+			t.Fatal(err)
+		}
+		// Appends leave the lines already written untouched
+		if i < 2 {
+			content := readArchiveFile(t, aaplFile)
+			if !strings.HasPrefix(content, written) || strings.Count(content, "\n") != i+1 {
+				t.Fatalf("archive file after append %d:\n%s", i+1, content)
+			}
+			written = content
+		}
+	}
+	if _, err := os.Stat(filepath.Join(archive.Dir, "MSFT", "2025-01-13.jsonl")); err != nil {
+		t.Errorf("MSFT snapshot not stored in its own directory: %v", err)
+	}
+
+	snapshots, err := archive.Snapshots("AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("%d snapshots, want 3", len(snapshots))
+	}
+	for i, s := range snapshots {
+		if want := archiveChain(float64(i + 1))[:2]; !s.CapturedAt.Equal(times[i]) || s.Underlying != "AAPL" || !reflect.DeepEqual(s.Options, want) {
+			t.Errorf("snapshot %d captured at %v with\n%+v\nwant %v with\n%+v", i, s.CapturedAt, s.Options, times[i], want)
+		}
+	}
+
+	// Both ends are inclusive
+	snapshots, err = archive.Snapshots("AAPL", times[1], times[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || !snapshots[0].CapturedAt.Equal(times[1]) {
+		t.Errorf("snapshots from the second capture on: %+v", snapshots)
+	}
+
+	history, err := archive.History("AAPL", "AAPL250117C00200000", time.Time{}, times[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Option.LatestQuote.BidPrice != 1 || history[1].Option.LatestQuote.BidPrice != 2 || !history[1].CapturedAt.Equal(times[1]) {
+		t.Errorf("history %+v, want the bids 1 and 2", history)
+	}
+	if history, _ := archive.History("AAPL", "MSFT250117C00420000", time.Time{}, time.Time{}); len(history) != 0 {
+		t.Errorf("history of a contract of another underlying %+v", history)
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want time.Time
+	}{
+		{times[0], times[0]},
+		{times[1].Add(time.Hour), times[1]},
+		{times[2].Add(-time.Nanosecond), times[1]},
+		{times[2].AddDate(1, 0, 0), times[2]},
+	} {
+		chain, err := archive.ChainAsOf("AAPL", tc.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !chain.CapturedAt.Equal(tc.want) {
+			t.Errorf("chain as of %v captured at %v, want %v", tc.at, chain.CapturedAt, tc.want)
+		}
+	}
+	if _, err := archive.ChainAsOf("AAPL", times[0].Add(-time.Nanosecond)); err == nil {
+		t.Error("expected an error for a time before the first snapshot")
+	}
+	if _, err := archive.Snapshots("../AAPL", time.Time{}, time.Time{}); err == nil {
+		t.Error("expected an error for an underlying outside the archive")
+	}
+}
+
+func TestOptionArchiveTornLine(t *testing.T) {
+	archive, err := OpenOptionArchive(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC)
+	if err := archive.Append(first, archiveChain(1)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(archive.Dir, "AAPL", "2025-01-10.jsonl")
+	complete := readArchiveFile(t, path)
+
+	// A crash during an append leaves half a line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"captured_at":"2025-01-10T15:01:00Z","underlying":"AAPL","options":[{"sym`)
+	file.Close()
+
+	// Readers skip the half-written line
+	snapshots, err := archive.Snapshots("AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || !snapshots[0].CapturedAt.Equal(first) {
+		t.Errorf("snapshots with a half-written last line: %+v", snapshots)
+	}
+
+	// The next append replaces it
+	second := first.Add(2 * time.Minute)
+	if err := archive.Append(second, archiveChain(3)); err != nil {
+		t.Fatal(err)
+	}
+	content := readArchiveFile(t, path)
+	if !strings.HasPrefix(content, complete) || strings.Count(content, "\n") != 2 {
+		t.Fatalf("archive file after the torn line:\n%s", content)
+	}
+	snapshots, err = archive.Snapshots("AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || !snapshots[1].CapturedAt.Equal(second) || snapshots[1].Options[0].LatestQuote.BidPrice != 3 {
+		t.Errorf("snapshots after the torn line: %+v", snapshots)
+	}
+
+	// A file holding nothing but a torn line is emptied before the append
+	next := filepath.Join(archive.Dir, "AAPL", "2025-01-13.jsonl")
+	if err := os.WriteFile(next, []byte(`{"captured_at":"2025-01-13T15:00:00Z","under`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Append(time.Date(2025, 1, 13, 15, 1, 0, 0, time.UTC), archiveChain(4)); err != nil {
+		t.Fatal(err)
+	}
+	if content := readArchiveFile(t, next); !strings.HasPrefix(content, `{"captured_at":"2025-01-13T15:01:00Z"`) || strings.Count(content, "\n") != 1 {
+		t.Errorf("archive file after a torn first line:\n%s", content)
+	}
+}
+
+func TestOptionArchiveCapture(t *testing.T) {
+	archive, err := OpenOptionArchive(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	options, err := archive.Capture(staticChain(archiveChain(1)), OptionURLReq{Ticker: "AAPL"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != 3 {
+		t.Errorf("Capture returned %d options, want 3", len(options))
+	}
+	chain, err := archive.ChainAsOf("MSFT", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain.CapturedAt.Before(before) || len(chain.Options) != 1 || chain.Options[0].Symbol != "MSFT250117C00420000" {
+		t.Errorf("captured %+v after %v", chain, before)
+	}
+}
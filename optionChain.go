@@ -0,0 +1,314 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of chain changes
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeQuote   = "quote"
+	ChangeIV      = "iv"
+)
+
+// ChainChange describes a change of one contract, Old is empty for added contracts and New for
+// removed ones
+type ChainChange struct {
+	Kind   string
+	Symbol string
+	Old    Option
+	New    Option
+}
+
+// OptionChain holds the contracts of an OptionURLReq indexed by expiry and strike and keeps them
+// current, from snapshots with Refresh or RefreshEvery and from an OptionStream with Stream.
+// Reads are safe while updates run. Returned options share their Quote, Trade and Greeks with the
+// chain, these are replaced rather than modified on updates, so treat them as read-only.
+type OptionChain struct {
+	Request OptionURLReq
+
+	// Subscribers are notified when the quote mid moves by more than QuoteThreshold or the
+	// implied volatility by more than IVThreshold
+	QuoteThreshold float64
+	IVThreshold    float64
+
+	mutex    sync.RWMutex
+	options  []Option
+	bySymbol map[string]int
+	byExpiry map[string][]int // sorted by strike
+	stream   *OptionStream
+	updated  time.Time
+
+	subMutex    sync.Mutex
+	subscribers map[chan ChainChange]bool
+}
+
+func NewOptionChain(optreq OptionURLReq) *OptionChain {
+	return &OptionChain{
+		Request:     optreq,
+		subscribers: make(map[chan ChainChange]bool),
+	}
+}
+
+// Refresh downloads the snapshots of the chain and notifies subscribers of the changes
+func (c *OptionChain) Refresh() error {
+	options, _, err := GetOptions(c.Request, -1)
+	if err != nil {
+		return fmt.Errorf("error refreshing option chain: %v", err)
+	}
+	c.set(options)
+	return nil
+}
+
+// RefreshEvery refreshes the chain on an interval until ctx is done. Failed refreshes keep the
+// previous data and are retried on the next tick.
+func (c *OptionChain) RefreshEvery(ctx context.Context, interval time.Duration) error {
+	if err := c.Refresh(); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := c.Refresh(); err != nil {
+				fmt.Printf("%v\n", err)
+			}
+		}
+	}
+}
+
+// Stream keeps the quotes and trades of the chain live from stream until ctx is done. Contracts
+// added by later refreshes are subscribed as well. Stream runs the stream and consumes its Events.
+func (c *OptionChain) Stream(ctx context.Context, stream *OptionStream) error {
+	c.mutex.Lock()
+	c.stream = stream
+	symbols := make([]string, 0, len(c.options))
+	for _, o := range c.options {
+		symbols = append(symbols, o.Symbol)
+	}
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.stream = nil
+		c.mutex.Unlock()
+	}()
+
+	if err := stream.Subscribe(OptionSubscription{Trades: symbols, Quotes: symbols}); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- stream.Run(ctx)
+	}()
+	for event := range stream.Events() {
+		c.apply(event)
+	}
+	return <-done
+}
+
+func (c *OptionChain) apply(event StreamEvent) {
+	c.mutex.Lock()
+	i, ok := c.bySymbol[event.EventSymbol()]
+	if !ok {
+		c.mutex.Unlock()
+		return
+	}
+	old := c.options[i]
+	if !applyOptionEvent(&c.options[i], event) {
+		c.mutex.Unlock()
+		return
+	}
+	c.updated = time.Now()
+	changes := c.diff(old, c.options[i])
+	c.mutex.Unlock()
+
+	c.notify(changes)
+}
+
+// set replaces the contracts and rebuilds the indexes
+func (c *OptionChain) set(options []Option) {
+	bySymbol := make(map[string]int, len(options))
+	byExpiry := make(map[string][]int)
+	for i, o := range options {
+		bySymbol[o.Symbol] = i
+		byExpiry[o.ExpirationDate] = append(byExpiry[o.ExpirationDate], i)
+	}
+	for _, indexes := range byExpiry {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return options[indexes[a]].StrikePrice < options[indexes[b]].StrikePrice
+		})
+	}
+
+	c.mutex.Lock()
+	var changes []ChainChange
+	var added []string
+	for _, o := range options {
+		i, ok := c.bySymbol[o.Symbol]
+		if !ok {
+			changes = append(changes, ChainChange{Kind: ChangeAdded, Symbol: o.Symbol, New: o})
+			added = append(added, o.Symbol)
+			continue
+		}
+		changes = append(changes, c.diff(c.options[i], o)...)
+	}
+	for _, o := range c.options {
+		if _, ok := bySymbol[o.Symbol]; !ok {
+			changes = append(changes, ChainChange{Kind: ChangeRemoved, Symbol: o.Symbol, Old: o})
+		}
+	}
+
+	c.options = options
+	c.bySymbol = bySymbol
+	c.byExpiry = byExpiry
+	c.updated = time.Now()
+	stream := c.stream
+	c.mutex.Unlock()
+
+	if stream != nil && len(added) > 0 {
+		if err := stream.Subscribe(OptionSubscription{Trades: added, Quotes: added}); err != nil {
+			fmt.Printf("Error subscribing new chain contracts: %v\n", err)
+		}
+	}
+	c.notify(changes)
+}
+
+// diff returns the quote and IV changes between two versions of a contract above the thresholds
+func (c *OptionChain) diff(before Option, after Option) []ChainChange {
+	var changes []ChainChange
+	if math.Abs(quoteMid(after.LatestQuote)-quoteMid(before.LatestQuote)) > c.QuoteThreshold {
+		changes = append(changes, ChainChange{Kind: ChangeQuote, Symbol: after.Symbol, Old: before, New: after})
+	}
+	if math.Abs(after.ImpliedVol-before.ImpliedVol) > c.IVThreshold {
+		changes = append(changes, ChainChange{Kind: ChangeIV, Symbol: after.Symbol, Old: before, New: after})
+	}
+	return changes
+}
+
+// quoteMid returns the mid of a two-sided quote and 0 otherwise
+func quoteMid(q *Quote) float64 {
+	if q == nil || q.BidPrice <= 0 || q.AskPrice <= 0 {
+		return 0
+	}
+	return (q.BidPrice + q.AskPrice) / 2
+}
+
+// Subscribe returns a channel receiving the changes of the chain and a function to unsubscribe.
+// Changes are dropped when the channel buffer is full.
+func (c *OptionChain) Subscribe(buffer int) (<-chan ChainChange, func()) {
+	ch := make(chan ChainChange, buffer)
+	c.subMutex.Lock()
+	c.subscribers[ch] = true
+	c.subMutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.subMutex.Lock()
+			delete(c.subscribers, ch)
+			c.subMutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (c *OptionChain) notify(changes []ChainChange) {
+	if len(changes) == 0 {
+		return
+	}
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+	for ch := range c.subscribers {
+		for _, change := range changes {
+			select {
+			case ch <- change:
+			default:
+			}
+		}
+	}
+}
+
+// Options returns a copy of all contracts
+func (c *OptionChain) Options() []Option {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return append([]Option(nil), c.options...)
+}
+
+// Get returns the contract with the OCC symbol
+func (c *OptionChain) Get(symbol string) (Option, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	i, ok := c.bySymbol[symbol]
+	if !ok {
+		return Option{}, false
+	}
+	return c.options[i], true
+}
+
+// Expirations returns the expiration dates in ascending order
+func (c *OptionChain) Expirations() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	dates := make([]string, 0, len(c.byExpiry))
+	for date := range c.byExpiry {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Expiry returns the contracts expiring on date (YYYY-MM-DD) ordered by strike
+func (c *OptionChain) Expiry(date string) []Option {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var options []Option
+	for _, i := range c.byExpiry[date] {
+		options = append(options, c.options[i])
+	}
+	return options
+}
+
+// Strikes returns the distinct strikes of an expiry in ascending order
+func (c *OptionChain) Strikes(date string) []float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var strikes []float64
+	for _, i := range c.byExpiry[date] {
+		strike := c.options[i].StrikePrice
+		if len(strikes) == 0 || strikes[len(strikes)-1] != strike {
+			strikes = append(strikes, strike)
+		}
+	}
+	return strikes
+}
+
+// At returns the contract of an expiry, strike and type ("call" or "put")
+func (c *OptionChain) At(date string, strike float64, optionType string) (Option, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	indexes := c.byExpiry[date]
+	k := sort.Search(len(indexes), func(k int) bool { return c.options[indexes[k]].StrikePrice >= strike })
+	for ; k < len(indexes) && c.options[indexes[k]].StrikePrice == strike; k++ {
+		if o := c.options[indexes[k]]; o.Type == optionType {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// Updated returns the time of the last refresh or stream update
+func (c *OptionChain) Updated() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.updated
+}
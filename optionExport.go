@@ -0,0 +1,441 @@
+package alpacaApiClient
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Value kinds of the flattened option columns
+const (
+	columnString = iota
+	columnFloat
+	columnInt
+	columnBool
+	columnTime
+)
+
+// optionColumn is one column of the flattened option schema. Columns of the market data
+// (bars, quote, trade, Greeks) are null while the struct is nil, zero timestamps are null too.
+type optionColumn struct {
+	name     string
+	kind     int
+	nullable bool
+	get      func(o *Option) (interface{}, bool)
+	set      func(o *Option, v interface{}) error
+}
+
+// column returns the column of the field returned by field, a *string, *float64, *int, *bool
+// or *time.Time. field returns nil for a nil struct unless create is set.
+func column(name string, field func(o *Option, create bool) interface{}) optionColumn {
+	c := optionColumn{name: name, nullable: field(&Option{}, false) == nil}
+	switch field(&Option{}, true).(type) {
+	case *string:
+		c.kind = columnString
+	case *float64:
+		c.kind = columnFloat
+	case *int:
+		c.kind = columnInt
+	case *bool:
+		c.kind = columnBool
+	case *time.Time:
+		c.kind, c.nullable = columnTime, true
+	}
+
+	c.get = func(o *Option) (interface{}, bool) {
+		switch p := field(o, false).(type) {
+		case *string:
+			return *p, true
+		case *float64:
+			return *p, true
+		case *int:
+			return int64(*p), true
+		case *bool:
+			return *p, true
+		case *time.Time:
+			return *p, !p.IsZero()
+		}
+		return nil, false
+	}
+	c.set = func(o *Option, v interface{}) error {
+		switch p := field(o, true).(type) {
+		case *string:
+			*p = v.(string)
+		case *float64:
+			*p = v.(float64)
+		case *int:
+			*p = int(v.(int64))
+		case *bool:
+			*p = v.(bool)
+		case *time.Time:
+			*p = v.(time.Time)
+		}
+		return nil
+	}
+	return c
+}
+
+func barColumns(prefix string, bar func(o *Option) **Bar) []optionColumn {
+	field := func(name string, f func(b *Bar) interface{}) optionColumn {
+		return column(prefix+name, func(o *Option, create bool) interface{} {
+			p := bar(o)
+			if *p == nil {
+				if !create {
+					return nil
+				}
+				*p = &Bar{}
+			}
+			return f(*p)
+		})
+	}
+	return []optionColumn{
+		field("_open", func(b *Bar) interface{} { return &b.Open }),
+		field("_high", func(b *Bar) interface{} { return &b.High }),
+		field("_low", func(b *Bar) interface{} { return &b.Low }),
+		field("_close", func(b *Bar) interface{} { return &b.Close }),
+		field("_volume", func(b *Bar) interface{} { return &b.Volume }),
+		field("_trades", func(b *Bar) interface{} { return &b.NumberOfTrades }),
+		field("_vwap", func(b *Bar) interface{} { return &b.VWAP }),
+		field("_timestamp", func(b *Bar) interface{} { return &b.Timestamp }),
+	}
+}
+
+func quoteColumns() []optionColumn {
+	field := func(name string, f func(q *Quote) interface{}) optionColumn {
+		return column("quote_"+name, func(o *Option, create bool) interface{} {
+			if o.LatestQuote == nil {
+				if !create {
+					return nil
+				}
+				o.LatestQuote = &Quote{}
+			}
+			return f(o.LatestQuote)
+		})
+	}
+	return []optionColumn{
+		field("ask_price", func(q *Quote) interface{} { return &q.AskPrice }),
+		field("ask_size", func(q *Quote) interface{} { return &q.AskSize }),
+		field("ask_exchange", func(q *Quote) interface{} { return &q.AskExchange }),
+		field("bid_price", func(q *Quote) interface{} { return &q.BidPrice }),
+		field("bid_size", func(q *Quote) interface{} { return &q.BidSize }),
+		field("bid_exchange", func(q *Quote) interface{} { return &q.BidExchange }),
+		field("condition", func(q *Quote) interface{} { return &q.Condition }),
+		field("timestamp", func(q *Quote) interface{} { return &q.Timestamp }),
+	}
+}
+
+func tradeColumns() []optionColumn {
+	field := func(name string, f func(t *Trade) interface{}) optionColumn {
+		return column("trade_"+name, func(o *Option, create bool) interface{} {
+			if o.LatestTrade == nil {
+				if !create {
+					return nil
+				}
+				o.LatestTrade = &Trade{}
+			}
+			return f(o.LatestTrade)
+		})
+	}
+	return []optionColumn{
+		field("price", func(t *Trade) interface{} { return &t.Price }),
+		field("size", func(t *Trade) interface{} { return &t.Size }),
+		field("exchange", func(t *Trade) interface{} { return &t.Exchange }),
+		field("condition", func(t *Trade) interface{} { return &t.Condition }),
+		field("timestamp", func(t *Trade) interface{} { return &t.Timestamp }),
+	}
+}
+
+func greeksColumns() []optionColumn {
+	field := func(name string, f func(g *Greeks) interface{}) optionColumn {
+		return column(name, func(o *Option, create bool) interface{} {
+			if o.Greeks == nil {
+				if !create {
+					return nil
+				}
+				o.Greeks = &Greeks{}
+			}
+			return f(o.Greeks)
+		})
+	}
+	return []optionColumn{
+		field("delta", func(g *Greeks) interface{} { return &g.Delta }),
+		field("gamma", func(g *Greeks) interface{} { return &g.Gamma }),
+		field("rho", func(g *Greeks) interface{} { return &g.Rho }),
+		field("theta", func(g *Greeks) interface{} { return &g.Theta }),
+		field("vega", func(g *Greeks) interface{} { return &g.Vega }),
+	}
+}
+
+// deliverablesColumn holds the deliverables as a JSON array
+var deliverablesColumn = optionColumn{
+	name:     "deliverables",
+	kind:     columnString,
+	nullable: true,
+	get: func(o *Option) (interface{}, bool) {
+		if len(o.Deliverables) == 0 {
+			return nil, false
+		}
+		data, _ := json.Marshal(o.Deliverables)
+		return string(data), true
+	},
+	set: func(o *Option, v interface{}) error {
+		if err := json.Unmarshal([]byte(v.(string)), &o.Deliverables); err != nil {
+			return fmt.Errorf("invalid deliverables: %v", err)
+		}
+		return nil
+	},
+}
+
+// optionColumns is the flattened option schema, append new columns at the end
+var optionColumns = func() []optionColumn {
+	contract := func(name string, f func(o *Option) interface{}) optionColumn {
+		return column(name, func(o *Option, _ bool) interface{} { return f(o) })
+	}
+	columns := []optionColumn{
+		contract("id", func(o *Option) interface{} { return &o.ID }),
+		contract("symbol", func(o *Option) interface{} { return &o.Symbol }),
+		contract("name", func(o *Option) interface{} { return &o.Name }),
+		contract("status", func(o *Option) interface{} { return &o.Status }),
+		contract("tradable", func(o *Option) interface{} { return &o.Tradable }),
+		contract("expiration_date", func(o *Option) interface{} { return &o.ExpirationDate }),
+		contract("root_symbol", func(o *Option) interface{} { return &o.RootSymbol }),
+		contract("underlying_symbol", func(o *Option) interface{} { return &o.UnderlyingSymbol }),
+		contract("underlying_asset_id", func(o *Option) interface{} { return &o.UnderlyingAssetID }),
+		contract("type", func(o *Option) interface{} { return &o.Type }),
+		contract("style", func(o *Option) interface{} { return &o.Style }),
+		contract("strike_price", func(o *Option) interface{} { return &o.StrikePrice }),
+		contract("multiplier", func(o *Option) interface{} { return &o.Multiplier }),
+		contract("size", func(o *Option) interface{} { return &o.Size }),
+		contract("open_interest", func(o *Option) interface{} { return &o.OpenInterest }),
+		contract("open_interest_date", func(o *Option) interface{} { return &o.OpenInterestDate }),
+		contract("close_price", func(o *Option) interface{} { return &o.ClosePrice }),
+		contract("close_price_date", func(o *Option) interface{} { return &o.ClosePriceDate }),
+		contract("ppind", func(o *Option) interface{} { return &o.PPIND }),
+		deliverablesColumn,
+		contract("implied_volatility", func(o *Option) interface{} { return &o.ImpliedVol }),
+	}
+	columns = append(columns, greeksColumns()...)
+	columns = append(columns, quoteColumns()...)
+	columns = append(columns, tradeColumns()...)
+	columns = append(columns, barColumns("daily_bar", func(o *Option) **Bar { return &o.DailyBar })...)
+	columns = append(columns, barColumns("prev_daily_bar", func(o *Option) **Bar { return &o.PrevDailyBar })...)
+	columns = append(columns, barColumns("minute_bar", func(o *Option) **Bar { return &o.MinuteBar })...)
+	return columns
+}()
+
+// OptionColumns returns the column names of the CSV and Parquet exports in order
+func OptionColumns() []string {
+	names := make([]string, len(optionColumns))
+	for i, c := range optionColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// convertValue converts an imported value to the kind of a column, e.g. an INT32 to int64
+func convertValue(v interface{}, kind int) (interface{}, error) {
+	switch kind {
+	case columnFloat:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case int64:
+			return float64(n), nil
+		}
+	case columnInt:
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case float64:
+			if n == float64(int64(n)) {
+				return int64(n), nil
+			}
+		}
+	case columnString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case columnBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case columnTime:
+		if t, ok := v.(time.Time); ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected value %v", v)
+}
+
+// WriteOptionsCSV writes options flattened to the columns of OptionColumns. Null values are
+// empty cells, timestamps RFC 3339 with nanoseconds.
+func WriteOptionsCSV(path string, options []Option) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating csv file: %v", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.Write(OptionColumns()); err != nil {
+		return err
+	}
+	record := make([]string, len(optionColumns))
+	for i := range options {
+		for j, c := range optionColumns {
+			record[j] = ""
+			v, ok := c.get(&options[i])
+			if !ok {
+				continue
+			}
+			switch v := v.(type) {
+			case string:
+				record[j] = v
+			case float64:
+				record[j] = strconv.FormatFloat(v, 'f', -1, 64)
+			case int64:
+				record[j] = strconv.FormatInt(v, 10)
+			case bool:
+				record[j] = strconv.FormatBool(v)
+			case time.Time:
+				record[j] = v.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadOptionsCSV reads options written by WriteOptionsCSV. Columns are matched by name, unknown
+// columns are ignored and missing ones left zero.
+func ReadOptionsCSV(path string) ([]Option, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening csv file: %v", err)
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %v", err)
+	}
+	columns := make([]*optionColumn, len(header))
+	for i, name := range header {
+		for j := range optionColumns {
+			if optionColumns[j].name == name {
+				columns[i] = &optionColumns[j]
+			}
+		}
+	}
+
+	var options []Option
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv: %v", err)
+		}
+
+		var o Option
+		for i, cell := range record {
+			c := columns[i]
+			if c == nil || cell == "" {
+				continue
+			}
+			var v interface{}
+			switch c.kind {
+			case columnString:
+				v = cell
+			case columnFloat:
+				v, err = strconv.ParseFloat(cell, 64)
+			case columnInt:
+				v, err = strconv.ParseInt(cell, 10, 64)
+			case columnBool:
+				v, err = strconv.ParseBool(cell)
+			case columnTime:
+				v, err = time.Parse(time.RFC3339Nano, cell)
+			}
+			if err == nil {
+				err = c.set(&o, v)
+			}
+			if err != nil {
+				line, _ := r.FieldPos(i)
+				return nil, fmt.Errorf("error parsing %s on line %d: %v", c.name, line, err)
+			}
+		}
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+// WriteOptionsParquet writes options flattened to the columns of OptionColumns as a single row
+// group of uncompressed, PLAIN encoded typed columns. Timestamps are UTC nanoseconds.
+func WriteOptionsParquet(path string, options []Option) error {
+	columns := make([]parquetColumn, len(optionColumns))
+	for i, c := range optionColumns {
+		columns[i] = parquetColumn{name: c.name, kind: c.kind, nullable: c.nullable}
+	}
+	data, err := encodeParquet(columns, len(options), func(row, col int) (interface{}, bool) {
+		return optionColumns[col].get(&options[row])
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing parquet file: %v", err)
+	}
+	return nil
+}
+
+// ReadOptionsParquet reads options from a Parquet file with the columns of OptionColumns, like
+// those of WriteOptionsParquet or pyarrow's write_table with its defaults. Flat files with PLAIN
+// or dictionary encoded pages, uncompressed or compressed with snappy, are supported.
+func ReadOptionsParquet(path string) ([]Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading parquet file: %v", err)
+	}
+	table, err := decodeParquet(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+
+	options := make([]Option, table.rows)
+	for i, pc := range table.columns {
+		var c *optionColumn
+		for j := range optionColumns {
+			if optionColumns[j].name == pc.name {
+				c = &optionColumns[j]
+			}
+		}
+		if c == nil {
+			continue
+		}
+		for row, v := range table.values[i] {
+			if v == nil {
+				continue
+			}
+			v, err := convertValue(v, c.kind)
+			if err == nil {
+				err = c.set(&options[row], v)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("column %s, row %d: %v", c.name, row+1, err)
+			}
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,203 @@
+package alpacaApiClient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var aaplDeliverables = []Deliverable{{
+	Type:                 "equity",
+	Symbol:               "AAPL",
+	AssetID:              "b0b6dd9d-8b9b-48a9-ba46-b9d54906e415",
+	Amount:               100,
+	AllocationPercentage: 100,
+	SettlementType:       "T+1",
+	SettlementMethod:     "CCC",
+}}
+
+// exportOptions returns a fully populated contract and one without market data
+func exportOptions() []Option {
+	quoteTime := time.Date(2025, 1, 10, 15, 59, 59, 123456789, time.UTC)
+	barTime := time.Date(2025, 1, 10, 5, 0, 0, 0, time.UTC)
+	return []Option{
+		{
+			ID:                "a1b2c3d4-0000-4000-8000-000000000001",
+			Symbol:            "AAPL250117C00200000",
+			Name:              "AAPL Jan 17 2025 200 Call",
+			Status:            "active",
+			Tradable:          true,
+			ExpirationDate:    "2025-01-17",
+			RootSymbol:        "AAPL",
+			UnderlyingSymbol:  "AAPL",
+			UnderlyingAssetID: "b0b6dd9d-8b9b-48a9-ba46-b9d54906e415",
+			Type:              "call",
+			Style:             "american",
+			StrikePrice:       200,
+			Multiplier:        100,
+			Size:              100,
+			OpenInterest:      1520,
+			OpenInterestDate:  "2025-01-09",
+			ClosePrice:        4.2,
+			ClosePriceDate:    "2025-01-09",
+			Deliverables:      aaplDeliverables,
+			ImpliedVol:        0.2513,
+			Greeks:            &Greeks{Delta: 0.5612, Gamma: 0.0321, Rho: 0.0123, Theta: -0.2101, Vega: 0.1402},
+			LatestQuote:       &Quote{AskPrice: 4.25, AskSize: 12, AskExchange: "C", BidPrice: 4.1, BidSize: 9, BidExchange: "W", Condition: "A", Timestamp: quoteTime},
+			LatestTrade:       &Trade{Price: 4.15, Size: 3, Exchange: "C", Condition: "I", Timestamp: quoteTime.Add(-time.Minute)},
+			DailyBar:          &Bar{Open: 3.9, High: 4.3, Low: 3.85, Close: 4.15, Volume: 812, NumberOfTrades: 97, VWAP: 4.08, Timestamp: barTime},
+			PrevDailyBar:      &Bar{Open: 4.4, High: 4.5, Low: 3.95, Close: 4.2, Volume: 1033, NumberOfTrades: 121, VWAP: 4.21, Timestamp: barTime.AddDate(0, 0, -1)},
+			MinuteBar:         &Bar{Open: 4.14, High: 4.16, Low: 4.14, Close: 4.15, Volume: 5, NumberOfTrades: 2, VWAP: 4.15},
+		},
+		{
+			ID:               "a1b2c3d4-0000-4000-8000-000000000002",
+			Symbol:           "AAPL250117P00200000",
+			Status:           "active",
+			ExpirationDate:   "2025-01-17",
+			RootSymbol:       "AAPL",
+			UnderlyingSymbol: "AAPL",
+			Type:             "put",
+			Style:            "american",
+			StrikePrice:      200,
+			Multiplier:       100,
+			Size:             100,
+		},
+	}
+}
+
+func TestOptionsCSVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.csv")
+	options := exportOptions()
+	if err := WriteOptionsCSV(path, options); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadOptionsCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, options) {
+		t.Errorf("read back\n%+v\nwant\n%+v", read, options)
+	}
+}
+
+func TestOptionsParquetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.parquet")
+	options := exportOptions()
+	if err := WriteOptionsParquet(path, options); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadOptionsParquet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, options) {
+		t.Errorf("read back\n%+v\nwant\n%+v", read, options)
+	}
+}
+
+func TestReadOptionsCSVInvalidDeliverables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.csv")
+	data := "symbol,deliverables\nAAPL250117C00200000,\"[{\"\"type\"\": \"\"equity\"\"\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadOptionsCSV(path)
+	if err == nil || !strings.Contains(err.Error(), "deliverables") || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error naming the deliverables column and line 2, got %v", err)
+	}
+}
+
+func TestReadOptionsCSVErrorLine(t *testing.T) {
+	// The quoted name of the first record spans three lines, the error is on line 5 of the file
+	path := filepath.Join(t.TempDir(), "options.csv")
+	data := "symbol,name,strike_price\n" +
+		"AAPL250117C00200000,\"AAPL\nJan 17 2025\n200 Call\",200\n" +
+		"AAPL250117P00200000,AAPL Jan 17 2025 200 Put,two hundred\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadOptionsCSV(path)
+	if err == nil || !strings.Contains(err.Error(), "strike_price on line 5") {
+		t.Errorf("expected an error for strike_price on line 5, got %v", err)
+	}
+}
+
+// The fixtures have the layout of a pandas DataFrame written by pyarrow, options.parquet without
+// compression and dictionaries, options_defaults.parquet with the defaults of write_table, see
+// testdata/options_parquet.py
+func TestReadOptionsParquetFixture(t *testing.T) {
+	want := []Option{
+		{
+			Symbol:           "AAPL250117C00200000",
+			UnderlyingSymbol: "AAPL",
+			Type:             "call",
+			ExpirationDate:   "2025-01-17",
+			StrikePrice:      200,
+			Multiplier:       100,
+			Tradable:         true,
+			OpenInterest:     1520,
+			Deliverables:     aaplDeliverables,
+			ImpliedVol:       0.25,
+			Greeks:           &Greeks{Delta: 0.5625},
+			LatestQuote:      &Quote{BidPrice: 4.1, AskPrice: 4.25, Timestamp: time.Date(2025, 1, 10, 15, 59, 59, 123456000, time.UTC)},
+		},
+		{
+			Symbol:           "AAPL250117P00200000",
+			UnderlyingSymbol: "AAPL",
+			Type:             "put",
+			ExpirationDate:   "2025-01-17",
+			StrikePrice:      200,
+			Multiplier:       100,
+			OpenInterest:     310,
+			ImpliedVol:       0.375,
+			Greeks:           &Greeks{Delta: -0.4375},
+			LatestQuote:      &Quote{BidPrice: 3.85, AskPrice: 3.95, Timestamp: time.Date(2025, 1, 10, 15, 59, 58, 1000, time.UTC)},
+		},
+		{
+			Symbol:           "AAPL250221C00210000",
+			UnderlyingSymbol: "AAPL",
+			Type:             "call",
+			ExpirationDate:   "2025-02-21",
+			StrikePrice:      210,
+			Multiplier:       100,
+			Tradable:         true,
+		},
+	}
+	for _, path := range []string{"testdata/options.parquet", "testdata/options_defaults.parquet"} {
+		options, err := ReadOptionsParquet(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !reflect.DeepEqual(options, want) {
+			t.Errorf("%s read\n%+v\nwant\n%+v", path, options, want)
+		}
+	}
+}
+
+func TestReadOptionsParquetInvalidDeliverables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.parquet")
+	options := exportOptions()
+	if err := WriteOptionsParquet(path, options); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Break the JSON of the first row without changing its length
+	i := strings.Index(string(data), `[{"type":"equity"`)
+	if i < 0 {
+		t.Fatal("deliverables not found in the file")
+	}
+	data[i] = '{'
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadOptionsParquet(path)
+	if err == nil || !strings.Contains(err.Error(), "deliverables") || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("expected an error naming the deliverables column and row 1, got %v", err)
+	}
+}
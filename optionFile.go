@@ -0,0 +1,102 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Format version of the files written by SaveOptions
+const optionFileVersion = 1
+
+// OptionsMeta describes a saved option chain
+type OptionsMeta struct {
+	Version    int       `json:"version"`
+	Underlying string    `json:"underlying"` // empty if the options have different underlyings
+	FetchedAt  time.Time `json:"fetched_at"`
+	Feed       string    `json:"feed,omitempty"`
+	Count      int       `json:"count"`
+
+	// Filters of the request the options were fetched with
+	ContractType string   `json:"contract_type,omitempty"`
+	StrikeRange  []int    `json:"strike_range,omitempty"`
+	DateRange    []string `json:"date_range,omitempty"`
+}
+
+type optionFile struct {
+	Meta    OptionsMeta `json:"meta"`
+	Options []Option    `json:"options"`
+}
+
+// NewOptionsMeta returns the metadata of options fetched with optreq from feed at fetchedAt,
+// the time GetOptions was called
+func NewOptionsMeta(optreq OptionURLReq, feed string, fetchedAt time.Time) OptionsMeta {
+	return OptionsMeta{
+		Underlying:   optreq.Ticker,
+		FetchedAt:    fetchedAt.UTC(),
+		Feed:         feed,
+		ContractType: optreq.Contract_type,
+		StrikeRange:  optreq.StrikeRange,
+		DateRange:    optreq.DateRange,
+	}
+}
+
+// SaveOptions writes options with their market data to path, stamped with fetchedAt, the time
+// GetOptions was called
+func SaveOptions(path string, options []Option, fetchedAt time.Time) error {
+	return SaveOptionsWithMeta(path, options, OptionsMeta{FetchedAt: fetchedAt.UTC()})
+}
+
+// SaveOptionsWithMeta writes options and meta to path. The version, count and an unset
+// underlying are filled in, the fetch time must be set.
+func SaveOptionsWithMeta(path string, options []Option, meta OptionsMeta) error {
+	if meta.FetchedAt.IsZero() {
+		return fmt.Errorf("fetch time of the options is not set")
+	}
+	meta.Version = optionFileVersion
+	meta.Count = len(options)
+	if meta.Underlying == "" && len(options) > 0 {
+		meta.Underlying = options[0].UnderlyingSymbol
+		for _, o := range options {
+			if o.UnderlyingSymbol != meta.Underlying {
+				meta.Underlying = ""
+				break
+			}
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating option file: %v", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(optionFile{Meta: meta, Options: options}); err != nil {
+		return fmt.Errorf("error encoding options: %v", err)
+	}
+	return file.Close()
+}
+
+// LoadOptions reads options and their metadata written by SaveOptions
+func LoadOptions(path string) ([]Option, OptionsMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, OptionsMeta{}, fmt.Errorf("error reading option file: %v", err)
+	}
+
+	var content optionFile
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, OptionsMeta{}, fmt.Errorf("error parsing option file %s: %v", path, err)
+	}
+	if content.Meta.Version == 0 {
+		return nil, content.Meta, fmt.Errorf("%s is not an option file written by SaveOptions", path)
+	}
+	if content.Meta.Version > optionFileVersion {
+		return nil, content.Meta, fmt.Errorf("option file version %d is newer than the supported version %d", content.Meta.Version, optionFileVersion)
+	}
+	if len(content.Options) != content.Meta.Count {
+		return nil, content.Meta, fmt.Errorf("option file %s is incomplete, %d of %d options", path, len(content.Options), content.Meta.Count)
+	}
+	return content.Options, content.Meta, nil
+}
@@ -0,0 +1,119 @@
+package alpacaApiClient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	options := exportOptions()
+	fetchedAt := time.Date(2025, 1, 10, 16, 0, 1, 987654321, time.UTC)
+	if err := SaveOptions(path, options, fetchedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	read, meta, err := LoadOptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, options) {
+		t.Errorf("read back\n%+v\nwant\n%+v", read, options)
+	}
+	want := OptionsMeta{Version: optionFileVersion, Underlying: "AAPL", FetchedAt: fetchedAt, Count: 2}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("meta %+v, want %+v", meta, want)
+	}
+	if read[1].DailyBar != nil || read[1].LatestQuote != nil || read[1].Greeks != nil {
+		t.Errorf("missing market data read back as %+v", read[1])
+	}
+
+	// JsonToOptions reads the same file
+	if legacy := JsonToOptions(path); !reflect.DeepEqual(legacy, options) {
+		t.Errorf("JsonToOptions read\n%+v\nwant\n%+v", legacy, options)
+	}
+}
+
+func TestSaveOptionsWithoutFetchTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	err := SaveOptionsWithMeta(path, exportOptions(), OptionsMeta{Feed: "indicative"})
+	if err == nil || !strings.Contains(err.Error(), "fetch time") {
+		t.Errorf("expected a missing fetch time error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file written without a fetch time: %v", err)
+	}
+}
+
+func TestLoadOptionsIncomplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	data := `{"meta":{"version":1,"underlying":"AAPL","fetched_at":"2025-01-10T16:00:00Z","count":2},"options":[{"symbol":"AAPL250117C00200000"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadOptions(path); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("expected an incomplete file error, got %v", err)
+	}
+}
+
+func TestJsonToOptionsLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "options.json")
+	data := `{"options": [
+		{
+			"id": "a1b2c3d4-0000-4000-8000-000000000001",
+			"symbol": "AAPL250117C00200000",
+			"underlying_symbol": "AAPL",
+			"type": "call",
+			"strike_price": 200,
+			"multiplier": 100,
+			"deliverables": [{"type": "equity", "symbol": "AAPL", "amount": "100", "allocation_percentage": "100"}],
+			"dailyBar": {"c": 4.15, "h": 4.3, "l": 3.85, "n": 97, "o": 3.9, "t": "2025-01-10T05:00:00Z", "v": 812, "vw": 4.08},
+			"greeks": {"delta": 0.5612, "gamma": 0.0321, "rho": 0.0123, "theta": -0.2101, "vega": 0.1402},
+			"impliedVolatility": 0.2513,
+			"latestQuote": {"ap": 4.25, "as": 12, "ax": "C", "bp": 4.1, "bs": 9, "bx": "W", "c": "A", "t": "2025-01-10T15:59:59.123456789Z"},
+			"latestTrade": {"c": "I", "p": 4.15, "s": 3, "t": "2025-01-10T15:58:59.123456789Z", "x": "C"}
+		},
+		{"symbol": "AAPL250117P00200000", "type": "put"}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := JsonToOptions(path)
+	if len(options) != 2 {
+		t.Fatalf("read %d options, want 2", len(options))
+	}
+	call := options[0]
+	quoteTime := time.Date(2025, 1, 10, 15, 59, 59, 123456789, time.UTC)
+	if call.Symbol != "AAPL250117C00200000" || call.UnderlyingSymbol != "AAPL" || call.StrikePrice != 200 || call.Multiplier != 100 {
+		t.Errorf("contract read as %+v", call)
+	}
+	if len(call.Deliverables) != 1 || call.Deliverables[0].Symbol != "AAPL" || call.Deliverables[0].Amount != 100 {
+		t.Errorf("deliverables read as %+v", call.Deliverables)
+	}
+	wantBar := Bar{Open: 3.9, High: 4.3, Low: 3.85, Close: 4.15, Volume: 812, NumberOfTrades: 97, VWAP: 4.08, Timestamp: time.Date(2025, 1, 10, 5, 0, 0, 0, time.UTC)}
+	if *call.DailyBar != wantBar {
+		t.Errorf("daily bar %+v, want %+v", *call.DailyBar, wantBar)
+	}
+	if *call.Greeks != (Greeks{Delta: 0.5612, Gamma: 0.0321, Rho: 0.0123, Theta: -0.2101, Vega: 0.1402}) || call.ImpliedVol != 0.2513 {
+		t.Errorf("greeks %+v and implied volatility %v", *call.Greeks, call.ImpliedVol)
+	}
+	wantQuote := Quote{AskPrice: 4.25, AskSize: 12, AskExchange: "C", BidPrice: 4.1, BidSize: 9, BidExchange: "W", Condition: "A", Timestamp: quoteTime}
+	if *call.LatestQuote != wantQuote {
+		t.Errorf("quote %+v, want %+v", *call.LatestQuote, wantQuote)
+	}
+	wantTrade := Trade{Price: 4.15, Size: 3, Exchange: "C", Condition: "I", Timestamp: quoteTime.Add(-time.Minute)}
+	if *call.LatestTrade != wantTrade {
+		t.Errorf("trade %+v, want %+v", *call.LatestTrade, wantTrade)
+	}
+
+	// Market data missing from the legacy layout reads as zero values
+	put := options[1]
+	if put.DailyBar == nil || *put.DailyBar != (Bar{}) || put.LatestQuote == nil || *put.LatestQuote != (Quote{}) || put.Greeks == nil || *put.Greeks != (Greeks{}) {
+		t.Errorf("missing market data read as %+v", put)
+	}
+}
@@ -0,0 +1,151 @@
+package alpacaApiClient
+
+import (
+	"fmt"
+	"math"
+)
+
+// PennyAllPrices lists the option classes quoted in 0.01 increments at every price. Add further
+// classes of the penny interval program to PennyProgram.
+var (
+	PennyAllPrices = map[string]bool{"SPY": true, "QQQ": true, "IWM": true}
+	PennyProgram   = map[string]bool{}
+)
+
+// Reference prices of a LimitPolicy
+const (
+	PriceBid = "bid"
+	PriceAsk = "ask"
+	PriceMid = "mid"
+)
+
+// LimitPolicy derives an option limit price from the latest quote: the reference price moved by
+// Ticks price increments, negative values lower the price
+type LimitPolicy struct {
+	Reference string
+	Ticks     int
+}
+
+var (
+	AtBid = LimitPolicy{Reference: PriceBid}
+	AtAsk = LimitPolicy{Reference: PriceAsk}
+	AtMid = LimitPolicy{Reference: PriceMid}
+)
+
+// MidPlusTicks returns a policy pricing n ticks above the mid, or below for negative n
+func MidPlusTicks(n int) LimitPolicy {
+	return LimitPolicy{Reference: PriceMid, Ticks: n}
+}
+
+// OptionTickSize returns the minimum price increment of an option trading at price
+func OptionTickSize(o Option, price float64) float64 {
+	root := o.RootSymbol
+	if root == "" {
+		root = o.UnderlyingSymbol
+	}
+	switch {
+	case PennyAllPrices[root]:
+		return 0.01
+	case PennyProgram[root] && price < 3:
+		return 0.01
+	case PennyProgram[root]:
+		return 0.05
+	case price < 3:
+		return 0.05
+	default:
+		return 0.10
+	}
+}
+
+// roundToTick rounds price to a valid increment, down for buys and up for sells so the
+// rounding never makes the order more aggressive
+func roundToTick(o Option, price float64, side string) float64 {
+	tick := OptionTickSize(o, price)
+	steps := price / tick
+	if side == SideBuy {
+		steps = math.Floor(steps + 1e-9)
+	} else {
+		steps = math.Ceil(steps - 1e-9)
+	}
+	return math.Round(steps*tick*100) / 100
+}
+
+// OptionLimitPrice computes the limit price of an order on o according to policy
+func OptionLimitPrice(o Option, side string, policy LimitPolicy) (float64, error) {
+	if o.LatestQuote == nil || o.LatestQuote.AskPrice <= 0 {
+		return 0, fmt.Errorf("no quote available for %s", o.Symbol)
+	}
+	q := o.LatestQuote
+
+	var price float64
+	switch policy.Reference {
+	case PriceBid:
+		if q.BidPrice <= 0 {
+			return 0, fmt.Errorf("no bid available for %s", o.Symbol)
+		}
+		price = q.BidPrice
+	case PriceAsk:
+		price = q.AskPrice
+	case PriceMid:
+		price = (q.BidPrice + q.AskPrice) / 2
+	default:
+		return 0, fmt.Errorf("invalid limit policy reference %q", policy.Reference)
+	}
+
+	price = roundToTick(o, price, side)
+	for i := 0; i < policy.Ticks; i++ {
+		price = math.Round((price+OptionTickSize(o, price))*100) / 100
+	}
+	for i := 0; i > policy.Ticks; i-- {
+		price = math.Round((price-OptionTickSize(o, price-0.01))*100) / 100
+	}
+
+	if price <= 0 {
+		return 0, fmt.Errorf("limit price for %s falls to %v", o.Symbol, price)
+	}
+	return price, nil
+}
+
+// OptionOrderReq builds a single-leg day limit order on o
+func OptionOrderReq(o Option, qty int, side string, intent string, policy LimitPolicy) (OrderReq, error) {
+	if qty <= 0 {
+		return OrderReq{}, fmt.Errorf("option orders require a positive whole quantity")
+	}
+	price, err := OptionLimitPrice(o, side, policy)
+	if err != nil {
+		return OrderReq{}, err
+	}
+	return OrderReq{
+		Symbol:         o.Symbol,
+		Qty:            float64(qty),
+		Side:           side,
+		Type:           OrderTypeLimit,
+		TimeInForce:    TimeInForceDay,
+		LimitPrice:     price,
+		PositionIntent: intent,
+	}, nil
+}
+
+func submitOptionOrder(o Option, qty int, side string, intent string, policy LimitPolicy) (Order, error) {
+	orderreq, err := OptionOrderReq(o, qty, side, intent, policy)
+	if err != nil {
+		return Order{}, err
+	}
+	return SubmitOrder(orderreq)
+}
+
+func BuyToOpen(o Option, qty int, policy LimitPolicy) (Order, error) {
+	return submitOptionOrder(o, qty, SideBuy, PositionIntentBuyToOpen, policy)
+}
+
+func SellToOpen(o Option, qty int, policy LimitPolicy) (Order, error) {
+	return submitOptionOrder(o, qty, SideSell, PositionIntentSellToOpen, policy)
+}
+
+func BuyToClose(o Option, qty int, policy LimitPolicy) (Order, error) {
+	return submitOptionOrder(o, qty, SideBuy, PositionIntentBuyToClose, policy)
+}
+
+func SellToClose(o Option, qty int, policy LimitPolicy) (Order, error) {
+	return submitOptionOrder(o, qty, SideSell, PositionIntentSellToClose, policy)
+}
@@ -0,0 +1,104 @@
+package alpacaApiClient
+
+import "testing"
+
+func TestOptionTickSize(t *testing.T) {
+	PennyProgram["XYZ"] = true
+	defer delete(PennyProgram, "XYZ")
+
+	tests := []struct {
+		root  string
+		price float64
+		tick  float64
+	}{
+		{"AAPL", 0.05, 0.05},
+		{"AAPL", 2.99, 0.05},
+		{"AAPL", 3.00, 0.10},
+		{"AAPL", 12.40, 0.10},
+		{"XYZ", 2.99, 0.01},
+		{"XYZ", 3.00, 0.05},
+		{"SPY", 2.99, 0.01},
+		{"SPY", 3.00, 0.01},
+		{"SPY", 250.00, 0.01},
+	}
+	for _, test := range tests {
+		o := Option{RootSymbol: test.root}
+		if tick := OptionTickSize(o, test.price); tick != test.tick {
+			t.Errorf("OptionTickSize(%s, %v) = %v, want %v", test.root, test.price, tick, test.tick)
+		}
+	}
+}
+
+func TestRoundToTick(t *testing.T) {
+	PennyProgram["XYZ"] = true
+	defer delete(PennyProgram, "XYZ")
+
+	tests := []struct {
+		root  string
+		price float64
+		side  string
+		want  float64
+	}{
+		{"AAPL", 2.97, SideBuy, 2.95},
+		{"AAPL", 2.97, SideSell, 3.00},
+		{"AAPL", 2.96, SideSell, 3.00},
+		{"AAPL", 3.00, SideBuy, 3.00},
+		{"AAPL", 3.00, SideSell, 3.00},
+		{"AAPL", 3.04, SideBuy, 3.00},
+		{"AAPL", 3.04, SideSell, 3.10},
+		{"AAPL", 0.30, SideBuy, 0.30},
+		{"XYZ", 2.994, SideBuy, 2.99},
+		{"XYZ", 2.994, SideSell, 3.00},
+		{"XYZ", 3.02, SideBuy, 3.00},
+		{"XYZ", 3.02, SideSell, 3.05},
+		{"SPY", 3.004, SideBuy, 3.00},
+		{"SPY", 3.004, SideSell, 3.01},
+	}
+	for _, test := range tests {
+		o := Option{RootSymbol: test.root}
+		if price := roundToTick(o, test.price, test.side); price != test.want {
+			t.Errorf("roundToTick(%s, %v, %s) = %v, want %v", test.root, test.price, test.side, price, test.want)
+		}
+	}
+}
+
+func TestOptionLimitPrice(t *testing.T) {
+	quoted := func(bid, ask float64) Option {
+		return Option{Symbol: "AAPL250117C00200000", RootSymbol: "AAPL", LatestQuote: &Quote{BidPrice: bid, AskPrice: ask}}
+	}
+
+	tests := []struct {
+		name   string
+		option Option
+		side   string
+		policy LimitPolicy
+		want   float64
+	}{
+		{"mid below 3", quoted(2.90, 3.00), SideBuy, AtMid, 2.95},
+		{"tick up to 3", quoted(2.90, 3.00), SideBuy, MidPlusTicks(1), 3.00},
+		{"tick up across 3", quoted(2.90, 3.00), SideBuy, MidPlusTicks(2), 3.10},
+		{"mid at 3", quoted(2.95, 3.05), SideSell, AtMid, 3.00},
+		{"tick down from 3", quoted(2.95, 3.05), SideSell, MidPlusTicks(-1), 2.95},
+		{"tick down above 3", quoted(3.20, 3.40), SideSell, MidPlusTicks(-2), 3.10},
+		{"sell mid rounds up", quoted(3.00, 3.10), SideSell, AtMid, 3.10},
+		{"buy mid rounds down", quoted(3.00, 3.10), SideBuy, AtMid, 3.00},
+		{"ask", quoted(4.10, 4.30), SideBuy, AtAsk, 4.30},
+	}
+	for _, test := range tests {
+		price, err := OptionLimitPrice(test.option, test.side, test.policy)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if price != test.want {
+			t.Errorf("%s: price %v, want %v", test.name, price, test.want)
+		}
+	}
+
+	if _, err := OptionLimitPrice(Option{Symbol: "AAPL250117C00200000"}, SideBuy, AtMid); err == nil {
+		t.Error("expected an error without quote")
+	}
+	if _, err := OptionLimitPrice(quoted(0.05, 0.10), SideBuy, LimitPolicy{Reference: PriceBid, Ticks: -1}); err == nil {
+		t.Error("expected an error for a limit price below one tick")
+	}
+}
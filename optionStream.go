@@ -0,0 +1,187 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Option stream feeds
+const (
+	FeedOPRA       = "opra"
+	FeedIndicative = "indicative"
+)
+
+// Most option symbols a connection may subscribe to quotes for
+var MaxOptionQuoteSymbols = 1000
+
+var occSymbol = regexp.MustCompile(`^[A-Z0-9]{1,6}\d{6}[CP]\d{8}$`)
+
+// OptionSubscription lists OCC symbols per channel, "*" subscribes to all trades but is not
+// allowed for quotes
+type OptionSubscription struct {
+	Trades []string
+	Quotes []string
+}
+
+func (sub OptionSubscription) channels() map[string][]string {
+	return map[string][]string{
+		ChannelTrades: sub.Trades,
+		ChannelQuotes: sub.Quotes,
+	}
+}
+
+type OptionTrade struct {
+	Symbol string
+	Trade
+}
+
+type OptionQuote struct {
+	Symbol string
+	Quote
+}
+
+func (e *OptionTrade) EventSymbol() string { return e.Symbol }
+func (e *OptionQuote) EventSymbol() string { return e.Symbol }
+
+// OptionStream streams real-time option trades and quotes of a feed (opra or indicative).
+// The stream only supports MessagePack.
+type OptionStream struct {
+	*marketStream
+}
+
+func NewOptionStream(feed string) *OptionStream {
+	s := newMarketStream(DataStreamURL+"/v1beta1/"+feed, []string{ChannelTrades, ChannelQuotes})
+	s.header.Set("Content-Type", "application/msgpack")
+	s.binary = true
+	s.encode = msgpackMarshal
+	s.decode = decodeMsgpackMessages
+	s.parse = parseOptionMessage
+	return &OptionStream{s}
+}
+
+// Subscribe adds OCC symbols, sent right away when connected and restored after reconnects
+func (s *OptionStream) Subscribe(sub OptionSubscription) error {
+	if err := s.validate(sub); err != nil {
+		return err
+	}
+	return s.update("subscribe", sub.channels())
+}
+
+func (s *OptionStream) Unsubscribe(sub OptionSubscription) error {
+	return s.update("unsubscribe", sub.channels())
+}
+
+// validate checks the symbols and the wildcard and quote limits of the options stream
+func (s *OptionStream) validate(sub OptionSubscription) error {
+	for _, symbol := range sub.Trades {
+		if symbol != "*" && !occSymbol.MatchString(symbol) {
+			return fmt.Errorf("invalid option symbol %s", symbol)
+		}
+	}
+
+	quotes := make(map[string]bool)
+	for _, symbol := range s.Subscriptions()[ChannelQuotes] {
+		quotes[symbol] = true
+	}
+	for _, symbol := range sub.Quotes {
+		if symbol == "*" {
+			return fmt.Errorf("wildcard subscriptions are not allowed for option quotes")
+		}
+		if !occSymbol.MatchString(symbol) {
+			return fmt.Errorf("invalid option symbol %s", symbol)
+		}
+		quotes[symbol] = true
+	}
+	if len(quotes) > MaxOptionQuoteSymbols {
+		return fmt.Errorf("option quote subscriptions exceed the limit of %d symbols", MaxOptionQuoteSymbols)
+	}
+	return nil
+}
+
+func parseOptionMessage(m map[string]interface{}) StreamEvent {
+	switch getString(m["T"]) {
+	case "t":
+		return &OptionTrade{
+			Symbol: getString(m["S"]),
+			Trade: Trade{
+				Condition: getString(m["c"]),
+				Price:     getFloat64(m["p"]),
+				Size:      getInt(m["s"]),
+				Timestamp: getTime(m["t"]),
+				Exchange:  getString(m["x"]),
+			},
+		}
+	case "q":
+		return &OptionQuote{
+			Symbol: getString(m["S"]),
+			Quote: Quote{
+				AskPrice:    getFloat64(m["ap"]),
+				AskSize:     getInt(m["as"]),
+				AskExchange: getString(m["ax"]),
+				BidPrice:    getFloat64(m["bp"]),
+				BidSize:     getInt(m["bs"]),
+				BidExchange: getString(m["bx"]),
+				Condition:   getString(m["c"]),
+				Timestamp:   getTime(m["t"]),
+			},
+		}
+	}
+	return nil
+}
+
+// Track subscribes to the trades and quotes of options and keeps their LatestQuote and
+// LatestTrade current in place until ctx is done. Track runs the stream and consumes Events.
+// Updates hold lock if it is not nil, readers of options should hold it as well.
+func (s *OptionStream) Track(ctx context.Context, options []Option, lock sync.Locker) error {
+	index := make(map[string][]int)
+	var symbols []string
+	for i, o := range options {
+		if _, ok := index[o.Symbol]; !ok {
+			symbols = append(symbols, o.Symbol)
+		}
+		index[o.Symbol] = append(index[o.Symbol], i)
+	}
+	if err := s.Subscribe(OptionSubscription{Trades: symbols, Quotes: symbols}); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	for event := range s.Events() {
+		if lock != nil {
+			lock.Lock()
+		}
+		for _, i := range index[event.EventSymbol()] {
+			applyOptionEvent(&options[i], event)
+		}
+		if lock != nil {
+			lock.Unlock()
+		}
+	}
+	return <-done
+}
+
+// applyOptionEvent stores a stream trade or quote on o unless o already holds a newer one
+func applyOptionEvent(o *Option, event StreamEvent) bool {
+	switch e := event.(type) {
+	case *OptionQuote:
+		if o.LatestQuote != nil && o.LatestQuote.Timestamp.After(e.Timestamp) {
+			return false
+		}
+		quote := e.Quote
+		o.LatestQuote = &quote
+	case *OptionTrade:
+		if o.LatestTrade != nil && o.LatestTrade.Timestamp.After(e.Timestamp) {
+			return false
+		}
+		trade := e.Trade
+		o.LatestTrade = &trade
+	default:
+		return false
+	}
+	return true
+}
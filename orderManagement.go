@@ -0,0 +1,197 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Largest page size of GET /v2/orders
+const maxOrdersPage = 500
+
+type ListOrdersReq struct {
+	Status    string // open (default), closed or all
+	Limit     int    // total number of orders to return, 0 for all
+	After     time.Time
+	Until     time.Time
+	Direction string // desc (default) or asc
+	Nested    bool
+	Symbols   []string
+	Side      string
+}
+
+type ReplaceOrderReq struct {
+	Qty           float64
+	TimeInForce   string
+	LimitPrice    float64
+	StopPrice     float64
+	Trail         float64
+	ClientOrderID string
+}
+
+// CancelStatus is the outcome of cancelling one order in CancelAllOrders
+type CancelStatus struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Body   Order  `json:"body"`
+}
+
+func GetOrder(orderID string) (Order, error) {
+	return defaultBroker.GetOrder(orderID)
+}
+
+func (b APIBroker) GetOrder(orderID string) (Order, error) {
+	return b.getOrder(b.tradingURL() + "/v2/orders/" + url.PathEscape(orderID))
+}
+
+func GetOrderByClientID(clientOrderID string) (Order, error) {
+	return defaultBroker.GetOrderByClientID(clientOrderID)
+}
+
+func (b APIBroker) GetOrderByClientID(clientOrderID string) (Order, error) {
+	return b.getOrder(b.tradingURL() + "/v2/orders:by_client_order_id?client_order_id=" + url.QueryEscape(clientOrderID))
+}
+
+// ListOrders returns the orders matching the request, paging through the results by submission time
+func ListOrders(listreq ListOrdersReq) ([]Order, error) {
+	return defaultBroker.ListOrders(listreq)
+}
+
+func (b APIBroker) ListOrders(listreq ListOrdersReq) ([]Order, error) {
+	direction := listreq.Direction
+	if direction == "" {
+		direction = "desc"
+	}
+	if direction != "asc" && direction != "desc" {
+		return nil, fmt.Errorf("invalid direction %q. Expected asc or desc", direction)
+	}
+
+	params := url.Values{}
+	params.Set("status", listreq.Status)
+	params.Set("direction", direction)
+	params.Set("symbols", strings.Join(listreq.Symbols, ","))
+	params.Set("side", listreq.Side)
+	if listreq.Nested {
+		params.Set("nested", "true")
+	}
+	after, until := listreq.After, listreq.Until
+
+	var orders []Order
+	seen := make(map[string]bool)
+	// Pages keep their full size, a smaller one could be filled by the orders repeated at the
+	// page boundary. The result is trimmed to Limit instead.
+	pageSize := maxOrdersPage
+	if listreq.Limit > 0 && listreq.Limit < pageSize {
+		pageSize = listreq.Limit
+	}
+	params.Set("limit", strconv.Itoa(pageSize))
+	for {
+		if !after.IsZero() {
+			params.Set("after", after.Format(time.RFC3339Nano))
+		}
+		if !until.IsZero() {
+			params.Set("until", until.Format(time.RFC3339Nano))
+		}
+
+		_, bodyStr, err := b.APIRequestMethod("GET", buildURL(b.tradingURL(), "/v2/orders", params), nil)
+		if err != nil {
+			return orders, err
+		}
+		var page []Order
+		if err := json.Unmarshal([]byte(bodyStr), &page); err != nil {
+			return orders, fmt.Errorf("error parsing orders: %v", err)
+		}
+
+		added := 0
+		for _, order := range page {
+			if seen[order.ID] {
+				continue
+			}
+			seen[order.ID] = true
+			orders = append(orders, order)
+			added++
+		}
+
+		if listreq.Limit > 0 && len(orders) >= listreq.Limit {
+			return orders[:listreq.Limit], nil
+		}
+		if len(page) < pageSize || added == 0 {
+			return orders, nil
+		}
+
+		// Continue from the last order of the page. The bounds are exclusive, so they are moved
+		// by 1ns to include other orders submitted at the same time, seen skips the repeated ones.
+		last := page[len(page)-1].SubmittedAt
+		if direction == "asc" {
+			after = last.Add(-time.Nanosecond)
+		} else {
+			until = last.Add(time.Nanosecond)
+		}
+	}
+}
+
+// ReplaceOrder changes an open order and returns the replacing order
+func ReplaceOrder(orderID string, replacereq ReplaceOrderReq) (Order, error) {
+	return defaultBroker.ReplaceOrder(orderID, replacereq)
+}
+
+func (b APIBroker) ReplaceOrder(orderID string, replacereq ReplaceOrderReq) (Order, error) {
+	payload := struct {
+		Qty           Decimal `json:"qty,omitempty"`
+		TimeInForce   string  `json:"time_in_force,omitempty"`
+		LimitPrice    Decimal `json:"limit_price,omitempty"`
+		StopPrice     Decimal `json:"stop_price,omitempty"`
+		Trail         Decimal `json:"trail,omitempty"`
+		ClientOrderID string  `json:"client_order_id,omitempty"`
+	}{
+		Qty:           Decimal(replacereq.Qty),
+		TimeInForce:   replacereq.TimeInForce,
+		LimitPrice:    Decimal(replacereq.LimitPrice),
+		StopPrice:     Decimal(replacereq.StopPrice),
+		Trail:         Decimal(replacereq.Trail),
+		ClientOrderID: replacereq.ClientOrderID,
+	}
+
+	var order Order
+	_, bodyStr, err := b.APIRequestMethod("PATCH", b.tradingURL()+"/v2/orders/"+url.PathEscape(orderID), payload)
+	if err != nil {
+		return order, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &order); err != nil {
+		return order, fmt.Errorf("error parsing order: %v", err)
+	}
+	return order, nil
+}
+
+func CancelOrder(orderID string) error {
+	return defaultBroker.CancelOrder(orderID)
+}
+
+func (b APIBroker) CancelOrder(orderID string) error {
+	_, _, err := b.APIRequestMethod("DELETE", b.tradingURL()+"/v2/orders/"+url.PathEscape(orderID), nil)
+	return err
+}
+
+// CancelAllOrders requests cancellation of all open orders and returns the per-order status codes
+func CancelAllOrders() ([]CancelStatus, error) {
+	return defaultBroker.CancelAllOrders()
+}
+
+func (b APIBroker) CancelAllOrders() ([]CancelStatus, error) {
+	_, bodyStr, err := b.APIRequestMethod("DELETE", b.tradingURL()+"/v2/orders", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []CancelStatus
+	if bodyStr == "" {
+		return statuses, nil
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &statuses); err != nil {
+		return nil, fmt.Errorf("error parsing cancel statuses: %v", err)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,390 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+
+	OrderTypeMarket       = "market"
+	OrderTypeLimit        = "limit"
+	OrderTypeStop         = "stop"
+	OrderTypeStopLimit    = "stop_limit"
+	OrderTypeTrailingStop = "trailing_stop"
+
+	TimeInForceDay = "day"
+	TimeInForceGTC = "gtc"
+	TimeInForceOPG = "opg"
+	TimeInForceCLS = "cls"
+	TimeInForceIOC = "ioc"
+	TimeInForceFOK = "fok"
+
+	OrderClassSimple  = "simple"
+	OrderClassBracket = "bracket"
+	OrderClassOCO     = "oco"
+	OrderClassOTO     = "oto"
+	OrderClassMLeg    = "mleg"
+
+	PositionIntentBuyToOpen   = "buy_to_open"
+	PositionIntentBuyToClose  = "buy_to_close"
+	PositionIntentSellToOpen  = "sell_to_open"
+	PositionIntentSellToClose = "sell_to_close"
+)
+
+// TakeProfit is the limit exit leg of an advanced order
+type TakeProfit struct {
+	LimitPrice float64
+}
+
+// StopLoss is the stop exit leg of an advanced order, a stop limit if LimitPrice is set
+type StopLoss struct {
+	StopPrice  float64
+	LimitPrice float64
+}
+
+// OrderReq describes an order to submit. Set either Qty or Notional (dollar amount).
+type OrderReq struct {
+	Symbol        string
+	Qty           float64
+	Notional      float64
+	Side          string
+	Type          string
+	TimeInForce   string
+	LimitPrice    float64
+	StopPrice     float64
+	TrailPrice    float64
+	TrailPercent  float64
+	ExtendedHours bool
+	ClientOrderID string
+
+	// Opening or closing side for option orders
+	PositionIntent string
+
+	// Advanced orders, OrderClass defaults to simple
+	OrderClass string
+	TakeProfit *TakeProfit
+	StopLoss   *StopLoss
+}
+
+// orderPayload is the request body of POST /v2/orders
+type orderPayload struct {
+	Symbol         string        `json:"symbol,omitempty"`
+	Qty            Decimal       `json:"qty,omitempty"`
+	Notional       Decimal       `json:"notional,omitempty"`
+	Side           string        `json:"side,omitempty"`
+	Type           string        `json:"type"`
+	TimeInForce    string        `json:"time_in_force"`
+	LimitPrice     Decimal       `json:"limit_price,omitempty"`
+	StopPrice      Decimal       `json:"stop_price,omitempty"`
+	TrailPrice     Decimal       `json:"trail_price,omitempty"`
+	TrailPercent   Decimal       `json:"trail_percent,omitempty"`
+	ExtendedHours  bool          `json:"extended_hours,omitempty"`
+	ClientOrderID  string        `json:"client_order_id,omitempty"`
+	OrderClass     string        `json:"order_class,omitempty"`
+	PositionIntent string        `json:"position_intent,omitempty"`
+	TakeProfit     *exitPayload  `json:"take_profit,omitempty"`
+	StopLoss       *exitPayload  `json:"stop_loss,omitempty"`
+	Legs           []mlegPayload `json:"legs,omitempty"`
+}
+
+// exitPayload is the take profit or stop loss leg of an advanced order
+type exitPayload struct {
+	StopPrice  Decimal `json:"stop_price,omitempty"`
+	LimitPrice Decimal `json:"limit_price,omitempty"`
+}
+
+type Order struct {
+	ID             string    `json:"id"`
+	ClientOrderID  string    `json:"client_order_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	SubmittedAt    time.Time `json:"submitted_at"`
+	FilledAt       time.Time `json:"filled_at"`
+	ExpiredAt      time.Time `json:"expired_at"`
+	CanceledAt     time.Time `json:"canceled_at"`
+	FailedAt       time.Time `json:"failed_at"`
+	ReplacedAt     time.Time `json:"replaced_at"`
+	ReplacedBy     string    `json:"replaced_by"`
+	Replaces       string    `json:"replaces"`
+	AssetID        string    `json:"asset_id"`
+	Symbol         string    `json:"symbol"`
+	AssetClass     string    `json:"asset_class"`
+	Notional       Decimal   `json:"notional"`
+	Qty            Decimal   `json:"qty"`
+	FilledQty      Decimal   `json:"filled_qty"`
+	FilledAvgPrice Decimal   `json:"filled_avg_price"`
+	OrderClass     string    `json:"order_class"`
+	Type           string    `json:"type"`
+	Side           string    `json:"side"`
+	PositionIntent string    `json:"position_intent"`
+	TimeInForce    string    `json:"time_in_force"`
+	LimitPrice     Decimal   `json:"limit_price"`
+	StopPrice      Decimal   `json:"stop_price"`
+	TrailPrice     Decimal   `json:"trail_price"`
+	TrailPercent   Decimal   `json:"trail_percent"`
+	HWM            Decimal   `json:"hwm"`
+	Status         string    `json:"status"`
+	ExtendedHours  bool      `json:"extended_hours"`
+	Legs           []Order   `json:"legs"`
+	RatioQty       Decimal   `json:"ratio_qty"`
+}
+
+// Validate checks the order request for combinations the API would reject
+func (r OrderReq) Validate() error {
+	if r.Symbol == "" {
+		return fmt.Errorf("order symbol is not set")
+	}
+	if r.Side != SideBuy && r.Side != SideSell {
+		return fmt.Errorf("invalid order side %q", r.Side)
+	}
+	if (r.Qty > 0) == (r.Notional > 0) {
+		return fmt.Errorf("exactly one of Qty and Notional must be set")
+	}
+
+	switch r.TimeInForce {
+	case TimeInForceDay, TimeInForceGTC, TimeInForceOPG, TimeInForceCLS, TimeInForceIOC, TimeInForceFOK:
+	default:
+		return fmt.Errorf("invalid time in force %q", r.TimeInForce)
+	}
+
+	switch r.Type {
+	case OrderTypeMarket:
+	case OrderTypeLimit:
+		if r.LimitPrice <= 0 {
+			return fmt.Errorf("limit order requires LimitPrice")
+		}
+	case OrderTypeStop:
+		if r.StopPrice <= 0 {
+			return fmt.Errorf("stop order requires StopPrice")
+		}
+	case OrderTypeStopLimit:
+		if r.LimitPrice <= 0 || r.StopPrice <= 0 {
+			return fmt.Errorf("stop limit order requires LimitPrice and StopPrice")
+		}
+	case OrderTypeTrailingStop:
+		if (r.TrailPrice > 0) == (r.TrailPercent > 0) {
+			return fmt.Errorf("trailing stop order requires exactly one of TrailPrice and TrailPercent")
+		}
+	default:
+		return fmt.Errorf("invalid order type %q", r.Type)
+	}
+
+	if r.Notional > 0 && (r.Type != OrderTypeMarket || r.TimeInForce != TimeInForceDay) {
+		return fmt.Errorf("notional orders must be market orders with time in force day")
+	}
+	if r.ExtendedHours && (r.Type != OrderTypeLimit || r.TimeInForce != TimeInForceDay) {
+		return fmt.Errorf("extended hours orders must be limit orders with time in force day")
+	}
+	if len(r.ClientOrderID) > 128 {
+		return fmt.Errorf("ClientOrderID exceeds 128 characters")
+	}
+
+	if err := validateIntent(r.Side, r.PositionIntent); err != nil {
+		return err
+	}
+
+	return r.validateClass()
+}
+
+func validateIntent(side string, intent string) error {
+	switch intent {
+	case "":
+	case PositionIntentBuyToOpen, PositionIntentBuyToClose:
+		if side != SideBuy {
+			return fmt.Errorf("position intent %s requires side buy", intent)
+		}
+	case PositionIntentSellToOpen, PositionIntentSellToClose:
+		if side != SideSell {
+			return fmt.Errorf("position intent %s requires side sell", intent)
+		}
+	default:
+		return fmt.Errorf("invalid position intent %q", intent)
+	}
+	return nil
+}
+
+// validateClass checks the legs of advanced orders. A buy opens a long (or closes a short), so its
+// take profit must lie above the entry and its stop loss below; a sell mirrors this.
+func (r OrderReq) validateClass() error {
+	switch r.OrderClass {
+	case "", OrderClassSimple:
+		if r.TakeProfit != nil || r.StopLoss != nil {
+			return fmt.Errorf("simple orders cannot have TakeProfit or StopLoss legs")
+		}
+		return nil
+	case OrderClassBracket:
+		if r.TakeProfit == nil || r.StopLoss == nil {
+			return fmt.Errorf("bracket orders require TakeProfit and StopLoss")
+		}
+	case OrderClassOCO:
+		if r.TakeProfit == nil || r.StopLoss == nil {
+			return fmt.Errorf("oco orders require TakeProfit and StopLoss")
+		}
+		if r.Type != OrderTypeLimit {
+			return fmt.Errorf("oco orders must be of type limit")
+		}
+	case OrderClassOTO:
+		if (r.TakeProfit == nil) == (r.StopLoss == nil) {
+			return fmt.Errorf("oto orders require exactly one of TakeProfit and StopLoss")
+		}
+	default:
+		return fmt.Errorf("invalid order class %q", r.OrderClass)
+	}
+
+	if r.Notional > 0 {
+		return fmt.Errorf("%s orders cannot be notional", r.OrderClass)
+	}
+	if r.TimeInForce != TimeInForceDay && r.TimeInForce != TimeInForceGTC {
+		return fmt.Errorf("%s orders require time in force day or gtc", r.OrderClass)
+	}
+	if r.ExtendedHours {
+		return fmt.Errorf("%s orders are not supported in extended hours", r.OrderClass)
+	}
+
+	// OCO orders are exits only, the sell side closes a long
+	long := r.Side == SideBuy
+	if r.OrderClass == OrderClassOCO {
+		long = r.Side == SideSell
+	}
+
+	if r.TakeProfit != nil && r.TakeProfit.LimitPrice <= 0 {
+		return fmt.Errorf("TakeProfit requires LimitPrice")
+	}
+	if r.StopLoss != nil {
+		if r.StopLoss.StopPrice <= 0 {
+			return fmt.Errorf("StopLoss requires StopPrice")
+		}
+		if r.StopLoss.LimitPrice > 0 && long && r.StopLoss.LimitPrice > r.StopLoss.StopPrice {
+			return fmt.Errorf("stop loss limit price %v must not be above its stop price %v for a long", r.StopLoss.LimitPrice, r.StopLoss.StopPrice)
+		}
+		if r.StopLoss.LimitPrice > 0 && !long && r.StopLoss.LimitPrice < r.StopLoss.StopPrice {
+			return fmt.Errorf("stop loss limit price %v must not be below its stop price %v for a short", r.StopLoss.LimitPrice, r.StopLoss.StopPrice)
+		}
+	}
+	if r.TakeProfit != nil && r.StopLoss != nil {
+		if long && r.TakeProfit.LimitPrice <= r.StopLoss.StopPrice {
+			return fmt.Errorf("take profit %v must be above stop loss %v for a long", r.TakeProfit.LimitPrice, r.StopLoss.StopPrice)
+		}
+		if !long && r.TakeProfit.LimitPrice >= r.StopLoss.StopPrice {
+			return fmt.Errorf("take profit %v must be below stop loss %v for a short", r.TakeProfit.LimitPrice, r.StopLoss.StopPrice)
+		}
+	}
+
+	// The entry price is only known for limit and stop entries
+	if r.OrderClass == OrderClassOCO {
+		return nil
+	}
+	entry := r.LimitPrice
+	if r.Type == OrderTypeStop {
+		entry = r.StopPrice
+	}
+	if entry <= 0 {
+		return nil
+	}
+	if r.TakeProfit != nil {
+		if long && r.TakeProfit.LimitPrice <= entry {
+			return fmt.Errorf("take profit %v must be above entry %v for a long", r.TakeProfit.LimitPrice, entry)
+		}
+		if !long && r.TakeProfit.LimitPrice >= entry {
+			return fmt.Errorf("take profit %v must be below entry %v for a short", r.TakeProfit.LimitPrice, entry)
+		}
+	}
+	if r.StopLoss != nil {
+		if long && r.StopLoss.StopPrice >= entry {
+			return fmt.Errorf("stop loss %v must be below entry %v for a long", r.StopLoss.StopPrice, entry)
+		}
+		if !long && r.StopLoss.StopPrice <= entry {
+			return fmt.Errorf("stop loss %v must be above entry %v for a short", r.StopLoss.StopPrice, entry)
+		}
+	}
+
+	return nil
+}
+
+func (r OrderReq) payload() orderPayload {
+	p := orderPayload{
+		Symbol:         r.Symbol,
+		Qty:            Decimal(r.Qty),
+		Notional:       Decimal(r.Notional),
+		Side:           r.Side,
+		Type:           r.Type,
+		TimeInForce:    r.TimeInForce,
+		LimitPrice:     Decimal(r.LimitPrice),
+		StopPrice:      Decimal(r.StopPrice),
+		TrailPrice:     Decimal(r.TrailPrice),
+		TrailPercent:   Decimal(r.TrailPercent),
+		ExtendedHours:  r.ExtendedHours,
+		ClientOrderID:  r.ClientOrderID,
+		OrderClass:     r.OrderClass,
+		PositionIntent: r.PositionIntent,
+	}
+	if r.TakeProfit != nil {
+		p.TakeProfit = &exitPayload{LimitPrice: Decimal(r.TakeProfit.LimitPrice)}
+	}
+	if r.StopLoss != nil {
+		p.StopLoss = &exitPayload{StopPrice: Decimal(r.StopLoss.StopPrice), LimitPrice: Decimal(r.StopLoss.LimitPrice)}
+	}
+	return p
+}
+
+// SubmitOrder validates and places an order
+func SubmitOrder(orderreq OrderReq) (Order, error) {
+	return defaultBroker.SubmitOrder(orderreq)
+}
+
+func (b APIBroker) SubmitOrder(orderreq OrderReq) (Order, error) {
+	if err := orderreq.Validate(); err != nil {
+		return Order{}, err
+	}
+
+	return b.postOrder(orderreq.payload())
+}
+
+func (b APIBroker) postOrder(payload orderPayload) (Order, error) {
+	var order Order
+
+	_, bodyStr, err := b.APIRequestMethod("POST", b.tradingURL()+"/v2/orders", payload)
+	if err != nil {
+		return order, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &order); err != nil {
+		return order, fmt.Errorf("error parsing order: %v", err)
+	}
+	return order, nil
+}
+
+// GetOrderTree fetches an order together with its child legs
+func GetOrderTree(orderID string) (Order, error) {
+	return defaultBroker.GetOrderTree(orderID)
+}
+
+func (b APIBroker) GetOrderTree(orderID string) (Order, error) {
+	return b.getOrder(b.tradingURL() + "/v2/orders/" + url.PathEscape(orderID) + "?nested=true")
+}
+
+func (b APIBroker) getOrder(endpoint string) (Order, error) {
+	var order Order
+
+	_, bodyStr, err := b.APIRequestMethod("GET", endpoint, nil)
+	if err != nil {
+		return order, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &order); err != nil {
+		return order, fmt.Errorf("error parsing order: %v", err)
+	}
+	return order, nil
+}
+
+// Flatten returns the order followed by all of its legs, depth first
+func (o Order) Flatten() []Order {
+	orders := []Order{o}
+	for _, leg := range o.Legs {
+		orders = append(orders, leg.Flatten()...)
+	}
+	return orders
+}
@@ -0,0 +1,872 @@
+package alpacaApiClient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Minimal Parquet support for flat tables. The writer produces one row group with one data page
+// (v1) per column, PLAIN values and RLE definition levels, no compression. The reader also takes
+// several row groups, dictionary pages, v2 data pages and snappy compression. The file metadata
+// is Thrift compact encoded.
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet encodings, compression codecs, page types and repetition types
+const (
+	parquetPlain           = 0
+	parquetPlainDictionary = 2
+	parquetRLE             = 3
+	parquetRLEDictionary   = 8
+	parquetUncompressed    = 0
+	parquetSnappy          = 1
+	parquetDataPage        = 0
+	parquetDictionaryPage  = 2
+	parquetDataPageV2      = 3
+	parquetRequired        = 0
+	parquetOptional        = 1
+)
+
+// Thrift compact protocol types
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+type parquetColumn struct {
+	name     string
+	kind     int
+	nullable bool
+}
+
+type parquetTable struct {
+	columns []parquetColumn
+	rows    int
+	values  [][]interface{} // per column, nil for null
+}
+
+func (c parquetColumn) physicalType() int {
+	switch c.kind {
+	case columnString:
+		return parquetByteArray
+	case columnFloat:
+		return parquetDouble
+	case columnBool:
+		return parquetBoolean
+	}
+	return parquetInt64
+}
+
+// thriftWriter writes Thrift compact protocol structs
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // last field ID of each open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) bool(id int16, v bool) {
+	if v {
+		w.field(id, thriftTrue)
+	} else {
+		w.field(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) binary(v string) {
+	w.varint(uint64(len(v)))
+	w.buf.WriteString(v)
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.binary(v)
+}
+
+// list writes a list header, followed by n elements of typ
+func (w *thriftWriter) list(id int16, typ byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		w.buf.WriteByte(0xf0 | typ)
+		w.varint(uint64(n))
+	}
+}
+
+// begin opens a struct, as field id or as list element if id is 0
+func (w *thriftWriter) begin(id int16) {
+	if id != 0 {
+		w.field(id, thriftStruct)
+	}
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+// encodeParquet encodes a table of rows whose values are returned by value
+func encodeParquet(columns []parquetColumn, rows int, value func(row, col int) (interface{}, bool)) ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(columns))
+	for col, c := range columns {
+		var levels []byte
+		var values bytes.Buffer
+		var bits []bool
+		for row := 0; row < rows; row++ {
+			v, ok := value(row, col)
+			if !ok {
+				if !c.nullable {
+					return nil, fmt.Errorf("null value in required column %s", c.name)
+				}
+				levels = append(levels, 0)
+				continue
+			}
+			levels = append(levels, 1)
+			switch v := v.(type) {
+			case string:
+				binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+				values.WriteString(v)
+			case float64:
+				binary.Write(&values, binary.LittleEndian, math.Float64bits(v))
+			case int64:
+				binary.Write(&values, binary.LittleEndian, v)
+			case bool:
+				bits = append(bits, v)
+			case time.Time:
+				binary.Write(&values, binary.LittleEndian, v.UnixNano())
+			}
+		}
+		if c.kind == columnBool {
+			packed := make([]byte, (len(bits)+7)/8)
+			for i, b := range bits {
+				if b {
+					packed[i/8] |= 1 << uint(i%8)
+				}
+			}
+			values.Write(packed)
+		}
+
+		var page bytes.Buffer
+		if c.nullable {
+			encoded := encodeLevels(levels)
+			binary.Write(&page, binary.LittleEndian, uint32(len(encoded)))
+			page.Write(encoded)
+		}
+		page.Write(values.Bytes())
+
+		w := &thriftWriter{}
+		w.begin(0)
+		w.i32(1, parquetDataPage)
+		w.i32(2, int32(page.Len()))
+		w.i32(3, int32(page.Len()))
+		w.begin(5)
+		w.i32(1, int32(rows))
+		w.i32(2, parquetPlain)
+		w.i32(3, parquetRLE)
+		w.i32(4, parquetRLE)
+		w.end()
+		w.end()
+
+		chunks[col].offset = int64(file.Len())
+		file.Write(w.buf.Bytes())
+		file.Write(page.Bytes())
+		chunks[col].size = int64(file.Len()) - chunks[col].offset
+	}
+
+	// FileMetaData
+	w := &thriftWriter{}
+	w.begin(0)
+	w.i32(1, 1)
+	w.list(2, thriftStruct, len(columns)+1)
+	w.begin(0)
+	w.string(4, "schema")
+	w.i32(5, int32(len(columns)))
+	w.end()
+	for _, c := range columns {
+		w.begin(0)
+		w.i32(1, int32(c.physicalType()))
+		if c.nullable {
+			w.i32(3, parquetOptional)
+		} else {
+			w.i32(3, parquetRequired)
+		}
+		w.string(4, c.name)
+		switch c.kind {
+		case columnString:
+			w.i32(6, 0) // UTF8
+			w.begin(10)
+			w.begin(1) // STRING
+			w.end()
+			w.end()
+		case columnTime:
+			w.begin(10)
+			w.begin(8) // TIMESTAMP
+			w.bool(1, true)
+			w.begin(2)
+			w.begin(3) // NANOS
+			w.end()
+			w.end()
+			w.end()
+			w.end()
+		}
+		w.end()
+	}
+	w.i64(3, int64(rows))
+
+	var total int64
+	for _, ch := range chunks {
+		total += ch.size
+	}
+	w.list(4, thriftStruct, 1)
+	w.begin(0)
+	w.list(1, thriftStruct, len(columns))
+	for i, c := range columns {
+		w.begin(0)
+		w.i64(2, chunks[i].offset)
+		w.begin(3)
+		w.i32(1, int32(c.physicalType()))
+		w.list(2, thriftI32, 2)
+		w.zigzag(parquetPlain)
+		w.zigzag(parquetRLE)
+		w.list(3, thriftBinary, 1)
+		w.binary(c.name)
+		w.i32(4, 0) // UNCOMPRESSED
+		w.i64(5, int64(rows))
+		w.i64(6, chunks[i].size)
+		w.i64(7, chunks[i].size)
+		w.i64(9, chunks[i].offset)
+		w.end()
+		w.end()
+	}
+	w.i64(2, total)
+	w.i64(3, int64(rows))
+	w.end()
+	w.string(6, "alpacaApiClient")
+	w.end()
+
+	file.Write(w.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(w.buf.Len()))
+	file.WriteString(parquetMagic)
+	return file.Bytes(), nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 as RLE runs
+func encodeLevels(levels []byte) []byte {
+	var buf []byte
+	var b [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = append(buf, b[:binary.PutUvarint(b[:], uint64(j-i)<<1)]...)
+		buf = append(buf, levels[i])
+		i = j
+	}
+	return buf
+}
+
+// decodeLevels decodes n definition levels of bit width 1 from the RLE/bit-packed hybrid encoding
+func decodeLevels(data []byte, n int) ([]byte, error) {
+	values, err := decodeHybrid(data, 1, n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid definition levels: %v", err)
+	}
+	levels := make([]byte, n)
+	for i, v := range values {
+		levels[i] = byte(v)
+	}
+	return levels, nil
+}
+
+// decodeHybrid decodes n values of width bits from the RLE/bit-packed hybrid encoding
+func decodeHybrid(data []byte, width, n int) ([]int, error) {
+	if width < 0 || width > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", width)
+	}
+	values := make([]int, 0, n)
+	pos := 0
+	for len(values) < n {
+		header, k := binary.Uvarint(data[pos:])
+		if k <= 0 {
+			return nil, fmt.Errorf("invalid run header")
+		}
+		pos += k
+		if header&1 == 1 {
+			// Groups of 8 values packed least significant bit first
+			count := int(header>>1) * 8
+			if pos+count*width/8 > len(data) {
+				return nil, fmt.Errorf("truncated bit-packed run")
+			}
+			for i := 0; i < count && len(values) < n; i++ {
+				v := 0
+				for b := 0; b < width; b++ {
+					bit := i*width + b
+					v |= int(data[pos+bit/8]>>uint(bit%8)&1) << uint(b)
+				}
+				values = append(values, v)
+			}
+			pos += count * width / 8
+		} else {
+			// A repeated value in the fewest bytes holding width bits
+			size := (width + 7) / 8
+			if pos+size > len(data) {
+				return nil, fmt.Errorf("truncated RLE run")
+			}
+			v := 0
+			for i := size - 1; i >= 0; i-- {
+				v = v<<8 | int(data[pos+i])
+			}
+			for i := uint64(0); i < header>>1 && len(values) < n; i++ {
+				values = append(values, v)
+			}
+			pos += size
+		}
+	}
+	return values, nil
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps of field ID to value: int64,
+// float64, bool, []byte, []interface{} or map[int16]interface{}
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of metadata")
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *thriftReader) varint() (uint64, error) {
+	v, k := binary.Uvarint(r.data[r.pos:])
+	if k <= 0 {
+		return 0, fmt.Errorf("invalid varint in metadata")
+	}
+	r.pos += k
+	return v, nil
+}
+
+func (r *thriftReader) zigzag() (int64, error) {
+	v, err := r.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) readStruct(depth int) (map[int16]interface{}, error) {
+	if depth > 32 {
+		return nil, fmt.Errorf("metadata nested too deeply")
+	}
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+		typ := b & 0x0f
+		if delta := b >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		switch typ {
+		case thriftTrue:
+			fields[id] = true
+		case thriftFalse:
+			fields[id] = false
+		default:
+			v, err := r.readValue(typ, depth)
+			if err != nil {
+				return nil, err
+			}
+			fields[id] = v
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		b, err := r.byte()
+		return b == thriftTrue, err
+	case thriftByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftDouble:
+		if r.pos+8 > len(r.data) {
+			return nil, fmt.Errorf("unexpected end of metadata")
+		}
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos-8:])), nil
+	case thriftBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("unexpected end of metadata")
+		}
+		r.pos += int(n)
+		return r.data[r.pos-int(n) : r.pos], nil
+	case thriftList, thriftSet:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(header >> 4)
+		if n == 15 {
+			if n, err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("invalid list size in metadata")
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.readValue(header&0x0f, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftMap:
+		n, err := r.varint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < 2*n; i++ {
+			typ := types >> 4
+			if i%2 == 1 {
+				typ = types & 0x0f
+			}
+			if _, err := r.readValue(typ, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return r.readStruct(depth + 1)
+	}
+	return nil, fmt.Errorf("unknown thrift type %d", typ)
+}
+
+func fieldInt(fields map[int16]interface{}, id int16) int64 {
+	v, _ := fields[id].(int64)
+	return v
+}
+
+func fieldStruct(fields map[int16]interface{}, id int16) map[int16]interface{} {
+	v, _ := fields[id].(map[int16]interface{})
+	return v
+}
+
+func fieldList(fields map[int16]interface{}, id int16) []interface{} {
+	v, _ := fields[id].([]interface{})
+	return v
+}
+
+// decodeParquet decodes a flat Parquet file into columns of values
+func decodeParquet(data []byte) (parquetTable, error) {
+	var table parquetTable
+	n := len(data)
+	if n < 12 || string(data[:4]) != parquetMagic || string(data[n-4:]) != parquetMagic {
+		return table, fmt.Errorf("not a parquet file")
+	}
+	size := int(binary.LittleEndian.Uint32(data[n-8:]))
+	if size > n-12 {
+		return table, fmt.Errorf("invalid footer length")
+	}
+	r := &thriftReader{data: data[n-8-size : n-8]}
+	meta, err := r.readStruct(0)
+	if err != nil {
+		return table, err
+	}
+
+	// Flat schemas only: the root and one element per column
+	schema := fieldList(meta, 2)
+	if len(schema) == 0 {
+		return table, fmt.Errorf("missing schema")
+	}
+	type leaf struct {
+		typ    int64
+		unit   time.Duration
+		maxDef int
+		column parquetColumn
+	}
+	var leaves []leaf
+	for _, e := range schema[1:] {
+		element, _ := e.(map[int16]interface{})
+		if fieldInt(element, 5) > 0 {
+			return table, fmt.Errorf("nested schemas are not supported")
+		}
+		l := leaf{typ: fieldInt(element, 1)}
+		name, _ := element[4].([]byte)
+		l.column.name = string(name)
+		switch fieldInt(element, 3) {
+		case parquetOptional:
+			l.maxDef = 1
+			l.column.nullable = true
+		case parquetRequired:
+		default:
+			return table, fmt.Errorf("repeated column %s is not supported", name)
+		}
+
+		switch l.typ {
+		case parquetByteArray:
+			l.column.kind = columnString
+		case parquetFloat, parquetDouble:
+			l.column.kind = columnFloat
+		case parquetBoolean:
+			l.column.kind = columnBool
+		case parquetInt32, parquetInt64:
+			l.column.kind = columnInt
+		default:
+			return table, fmt.Errorf("column %s has unsupported type %d", name, l.typ)
+		}
+
+		// Timestamps from the logical type or the legacy converted type
+		if ts := fieldStruct(fieldStruct(element, 10), 8); ts != nil {
+			unit := fieldStruct(ts, 2)
+			switch {
+			case fieldStruct(unit, 1) != nil:
+				l.unit = time.Millisecond
+			case fieldStruct(unit, 2) != nil:
+				l.unit = time.Microsecond
+			default:
+				l.unit = time.Nanosecond
+			}
+		} else if converted, ok := element[6].(int64); ok && (converted == 9 || converted == 10) {
+			l.unit = time.Millisecond
+			if converted == 10 {
+				l.unit = time.Microsecond
+			}
+		}
+		if l.unit != 0 {
+			l.column.kind = columnTime
+		}
+		leaves = append(leaves, l)
+		table.columns = append(table.columns, l.column)
+	}
+	table.values = make([][]interface{}, len(leaves))
+
+	for _, g := range fieldList(meta, 4) {
+		group, _ := g.(map[int16]interface{})
+		rows := int(fieldInt(group, 3))
+		chunks := fieldList(group, 1)
+		if len(chunks) != len(leaves) {
+			return table, fmt.Errorf("row group has %d columns, schema %d", len(chunks), len(leaves))
+		}
+		for i, ch := range chunks {
+			chunk, _ := ch.(map[int16]interface{})
+			values, err := decodeColumnChunk(data, fieldStruct(chunk, 3), leaves[i].typ, leaves[i].maxDef, leaves[i].unit)
+			if err != nil {
+				return table, fmt.Errorf("column %s: %v", leaves[i].column.name, err)
+			}
+			if len(values) != rows {
+				return table, fmt.Errorf("column %s has %d values, row group %d", leaves[i].column.name, len(values), rows)
+			}
+			table.values[i] = append(table.values[i], values...)
+		}
+		table.rows += rows
+	}
+	return table, nil
+}
+
+// decodeColumnChunk decodes the dictionary and data pages of a column chunk
+func decodeColumnChunk(data []byte, meta map[int16]interface{}, typ int64, maxDef int, unit time.Duration) ([]interface{}, error) {
+	if meta == nil {
+		return nil, fmt.Errorf("missing column metadata")
+	}
+	codec := fieldInt(meta, 4)
+	if codec != parquetUncompressed && codec != parquetSnappy {
+		return nil, fmt.Errorf("compression codec %d is not supported, write the file uncompressed or with snappy", codec)
+	}
+
+	count := int(fieldInt(meta, 5))
+	pos := int(fieldInt(meta, 9))
+	if offset := int(fieldInt(meta, 11)); offset > 0 && offset < pos {
+		pos = offset
+	}
+	var dictionary, values []interface{}
+	for len(values) < count {
+		if pos < 0 || pos >= len(data) {
+			return nil, fmt.Errorf("page offset out of range")
+		}
+		r := &thriftReader{data: data[pos:]}
+		header, err := r.readStruct(0)
+		if err != nil {
+			return nil, err
+		}
+		pos += r.pos
+		size := int(fieldInt(header, 3))
+		if size < 0 || pos+size > len(data) {
+			return nil, fmt.Errorf("page size out of range")
+		}
+		page := data[pos : pos+size]
+		pos += size
+		uncompressed := int(fieldInt(header, 2))
+
+		var n int
+		var encoding int64
+		var levels []byte
+		switch fieldInt(header, 1) {
+		case parquetDictionaryPage:
+			if page, err = decompressPage(page, codec, uncompressed); err != nil {
+				return nil, err
+			}
+			if dictionary, err = decodePlain(page, typ, int(fieldInt(fieldStruct(header, 7), 1))); err != nil {
+				return nil, fmt.Errorf("dictionary page: %v", err)
+			}
+			continue
+		case parquetDataPage:
+			if page, err = decompressPage(page, codec, uncompressed); err != nil {
+				return nil, err
+			}
+			pageHeader := fieldStruct(header, 5)
+			n = int(fieldInt(pageHeader, 1))
+			encoding = fieldInt(pageHeader, 2)
+			if maxDef > 0 {
+				if len(page) < 4 {
+					return nil, fmt.Errorf("truncated page")
+				}
+				length := int(binary.LittleEndian.Uint32(page))
+				if length > len(page)-4 {
+					return nil, fmt.Errorf("truncated page")
+				}
+				if levels, err = decodeLevels(page[4:4+length], n); err != nil {
+					return nil, err
+				}
+				page = page[4+length:]
+			}
+		case parquetDataPageV2:
+			// The levels precede the values without a length and are never compressed
+			pageHeader := fieldStruct(header, 8)
+			n = int(fieldInt(pageHeader, 1))
+			encoding = fieldInt(pageHeader, 4)
+			defLength, repLength := int(fieldInt(pageHeader, 5)), int(fieldInt(pageHeader, 6))
+			if defLength < 0 || repLength < 0 || repLength+defLength > len(page) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			if maxDef > 0 {
+				if levels, err = decodeLevels(page[repLength:repLength+defLength], n); err != nil {
+					return nil, err
+				}
+			}
+			page = page[repLength+defLength:]
+			if compressed, ok := pageHeader[7].(bool); !ok || compressed {
+				if page, err = decompressPage(page, codec, uncompressed-repLength-defLength); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			continue
+		}
+
+		if levels == nil {
+			levels = make([]byte, n)
+			for i := range levels {
+				levels[i] = 1
+			}
+		}
+		defined := 0
+		for _, level := range levels {
+			defined += int(level)
+		}
+
+		var decoded []interface{}
+		switch encoding {
+		case parquetPlain:
+			decoded, err = decodePlain(page, typ, defined)
+		case parquetPlainDictionary, parquetRLEDictionary:
+			decoded, err = decodeDictionaryIndices(page, dictionary, defined)
+		default:
+			err = fmt.Errorf("encoding %d is not supported", encoding)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, level := range levels {
+			if level == 0 {
+				values = append(values, nil)
+				continue
+			}
+			v := decoded[0]
+			decoded = decoded[1:]
+			if unit != 0 {
+				ticks, _ := v.(int64)
+				v = time.Unix(0, ticks*int64(unit)).UTC()
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// decompressPage returns the uncompressed data of a page
+func decompressPage(page []byte, codec int64, size int) ([]byte, error) {
+	if codec == parquetUncompressed {
+		return page, nil
+	}
+	data, err := snappyDecode(page)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("page has %d bytes uncompressed, want %d", len(data), size)
+	}
+	return data, nil
+}
+
+// decodeDictionaryIndices looks up n values of the RLE/bit-packed dictionary indices in page,
+// which start with their bit width
+func decodeDictionaryIndices(page []byte, dictionary []interface{}, n int) ([]interface{}, error) {
+	if dictionary == nil {
+		return nil, fmt.Errorf("dictionary encoded page without a dictionary")
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if len(page) < 1 {
+		return nil, fmt.Errorf("truncated page")
+	}
+	indices, err := decodeHybrid(page[1:], int(page[0]), n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary indices: %v", err)
+	}
+	values := make([]interface{}, n)
+	for i, index := range indices {
+		if index >= len(dictionary) {
+			return nil, fmt.Errorf("dictionary index %d out of range", index)
+		}
+		values[i] = dictionary[index]
+	}
+	return values, nil
+}
+
+// decodePlain decodes n PLAIN encoded values of a physical type
+func decodePlain(page []byte, typ int64, n int) ([]interface{}, error) {
+	values := make([]interface{}, 0, n)
+	var bit int
+	for len(values) < n {
+		var v interface{}
+		var width int
+		switch typ {
+		case parquetBoolean:
+			if bit/8 >= len(page) {
+				return nil, fmt.Errorf("truncated page")
+			}
+			v = page[bit/8]>>uint(bit%8)&1 == 1
+			bit++
+		case parquetInt32:
+			width = 4
+			if len(page) >= width {
+				v = int64(int32(binary.LittleEndian.Uint32(page)))
+			}
+		case parquetInt64:
+			width = 8
+			if len(page) >= width {
+				v = int64(binary.LittleEndian.Uint64(page))
+			}
+		case parquetFloat:
+			width = 4
+			if len(page) >= width {
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(page)))
+			}
+		case parquetDouble:
+			width = 8
+			if len(page) >= width {
+				v = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			}
+		case parquetByteArray:
+			if len(page) >= 4 {
+				length := int(binary.LittleEndian.Uint32(page))
+				if length <= len(page)-4 {
+					v = string(page[4 : 4+length])
+					width = 4 + length
+				}
+			}
+		}
+		if v == nil {
+			return nil, fmt.Errorf("truncated page")
+		}
+		page = page[width:]
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,200 @@
+package alpacaApiClient
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+type PortfolioHistoryReq struct {
+	Period            string // e.g. 1D, 1W, 1M, 1A
+	Timeframe         string // 1Min, 5Min, 15Min, 1H or 1D
+	IntradayReporting string // market_hours, extended_hours or continuous
+	PnlReset          string // per_day or no_reset
+	Start             time.Time
+	End               time.Time
+}
+
+// EquityPoint is one sample of the equity curve
+type EquityPoint struct {
+	Time          time.Time
+	Equity        float64
+	ProfitLoss    float64
+	ProfitLossPct float64
+}
+
+type PortfolioHistory struct {
+	Timeframe     string
+	BaseValue     float64
+	BaseValueAsOf string
+	Points        []EquityPoint
+}
+
+func GetPortfolioHistory(histreq PortfolioHistoryReq) (PortfolioHistory, error) {
+	return defaultBroker.GetPortfolioHistory(histreq)
+}
+
+func (b APIBroker) GetPortfolioHistory(histreq PortfolioHistoryReq) (PortfolioHistory, error) {
+	var history PortfolioHistory
+
+	params := url.Values{}
+	params.Set("period", histreq.Period)
+	params.Set("timeframe", histreq.Timeframe)
+	params.Set("intraday_reporting", histreq.IntradayReporting)
+	params.Set("pnl_reset", histreq.PnlReset)
+	if !histreq.Start.IsZero() {
+		params.Set("start", histreq.Start.Format(time.RFC3339))
+	}
+	if !histreq.End.IsZero() {
+		params.Set("end", histreq.End.Format(time.RFC3339))
+	}
+
+	_, bodyStr, err := b.APIRequestMethod("GET", buildURL(b.tradingURL(), "/v2/account/portfolio/history", params), nil)
+	if err != nil {
+		return history, err
+	}
+
+	var data struct {
+		Timestamp     []int64    `json:"timestamp"`
+		Equity        []*float64 `json:"equity"`
+		ProfitLoss    []*float64 `json:"profit_loss"`
+		ProfitLossPct []*float64 `json:"profit_loss_pct"`
+		BaseValue     float64    `json:"base_value"`
+		BaseValueAsOf string     `json:"base_value_asof"`
+		Timeframe     string     `json:"timeframe"`
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &data); err != nil {
+		return history, fmt.Errorf("error parsing portfolio history: %v", err)
+	}
+
+	history.Timeframe = data.Timeframe
+	history.BaseValue = data.BaseValue
+	history.BaseValueAsOf = data.BaseValueAsOf
+
+	value := func(values []*float64, i int) float64 {
+		if i < len(values) && values[i] != nil {
+			return *values[i]
+		}
+		return 0
+	}
+	for i, ts := range data.Timestamp {
+		// Samples without equity lie outside the account's lifetime
+		if i >= len(data.Equity) || data.Equity[i] == nil {
+			continue
+		}
+		history.Points = append(history.Points, EquityPoint{
+			Time:          time.Unix(ts, 0).In(Market),
+			Equity:        *data.Equity[i],
+			ProfitLoss:    value(data.ProfitLoss, i),
+			ProfitLossPct: value(data.ProfitLossPct, i),
+		})
+	}
+
+	return history, nil
+}
+
+// DailyReturns returns the simple returns between the closing equity of consecutive market dates
+func (h PortfolioHistory) DailyReturns() []float64 {
+	var closes []float64
+	var lastDate string
+	for _, p := range h.Points {
+		date := p.Time.In(Market).Format("2006-01-02")
+		if date == lastDate {
+			closes[len(closes)-1] = p.Equity
+			continue
+		}
+		closes = append(closes, p.Equity)
+		lastDate = date
+	}
+
+	var returns []float64
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] == 0 {
+			continue
+		}
+		returns = append(returns, closes[i]/closes[i-1]-1)
+	}
+	return returns
+}
+
+// MaxDrawdown returns the largest peak to trough decline as a fraction of the peak, with the
+// times of the peak and the trough
+func (h PortfolioHistory) MaxDrawdown() (float64, time.Time, time.Time) {
+	var maxDrawdown float64
+	var peak EquityPoint
+	var maxPeak, maxTrough time.Time
+	for i, p := range h.Points {
+		if i == 0 || p.Equity > peak.Equity {
+			peak = p
+			continue
+		}
+		if peak.Equity <= 0 {
+			continue
+		}
+		drawdown := (peak.Equity - p.Equity) / peak.Equity
+		if drawdown > maxDrawdown {
+			maxDrawdown = drawdown
+			maxPeak = peak.Time
+			maxTrough = p.Time
+		}
+	}
+	return maxDrawdown, maxPeak, maxTrough
+}
+
+// Sharpe returns the annualized Sharpe ratio of the daily returns for an annual risk free rate,
+// assuming 252 trading days per year
+func (h PortfolioHistory) Sharpe(riskFreeRate float64) float64 {
+	returns := h.DailyReturns()
+	if len(returns) < 2 {
+		return 0
+	}
+
+	dailyRiskFree := riskFreeRate / 252
+	var mean float64
+	for _, r := range returns {
+		mean += r - dailyRiskFree
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += math.Pow(r-dailyRiskFree-mean, 2)
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+	if stddev == 0 {
+		return 0
+	}
+	return mean / stddev * math.Sqrt(252)
+}
+
+// WriteCSV exports the equity curve with a header of time, equity, profit_loss, profit_loss_pct
+func (h PortfolioHistory) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating csv file: %v", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.Write([]string{"time", "equity", "profit_loss", "profit_loss_pct"}); err != nil {
+		return err
+	}
+	for _, p := range h.Points {
+		record := []string{
+			p.Time.Format(time.RFC3339),
+			strconv.FormatFloat(p.Equity, 'f', -1, 64),
+			strconv.FormatFloat(p.ProfitLoss, 'f', -1, 64),
+			strconv.FormatFloat(p.ProfitLossPct, 'f', -1, 64),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
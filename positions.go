@@ -0,0 +1,233 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+type Position struct {
+	AssetID                string  `json:"asset_id"`
+	Symbol                 string  `json:"symbol"`
+	Exchange               string  `json:"exchange"`
+	AssetClass             string  `json:"asset_class"`
+	AvgEntryPrice          Decimal `json:"avg_entry_price"`
+	Qty                    Decimal `json:"qty"`
+	QtyAvailable           Decimal `json:"qty_available"`
+	Side                   string  `json:"side"`
+	MarketValue            Decimal `json:"market_value"`
+	CostBasis              Decimal `json:"cost_basis"`
+	UnrealizedPL           Decimal `json:"unrealized_pl"`
+	UnrealizedPLPC         Decimal `json:"unrealized_plpc"`
+	UnrealizedIntradayPL   Decimal `json:"unrealized_intraday_pl"`
+	UnrealizedIntradayPLPC Decimal `json:"unrealized_intraday_plpc"`
+	CurrentPrice           Decimal `json:"current_price"`
+	LastdayPrice           Decimal `json:"lastday_price"`
+	ChangeToday            Decimal `json:"change_today"`
+	AssetMarginable        bool    `json:"asset_marginable"`
+}
+
+// CloseStatus is the outcome of closing one position in CloseAllPositions
+type CloseStatus struct {
+	Symbol string `json:"symbol"`
+	Status int    `json:"status"`
+	Body   Order  `json:"body"`
+}
+
+func ListPositions() ([]Position, error) {
+	return defaultBroker.ListPositions()
+}
+
+func (b APIBroker) ListPositions() ([]Position, error) {
+	_, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/positions", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	if err := json.Unmarshal([]byte(bodyStr), &positions); err != nil {
+		return nil, fmt.Errorf("error parsing positions: %v", err)
+	}
+	return positions, nil
+}
+
+func GetPosition(symbolOrAssetID string) (Position, error) {
+	return defaultBroker.GetPosition(symbolOrAssetID)
+}
+
+func (b APIBroker) GetPosition(symbolOrAssetID string) (Position, error) {
+	var position Position
+
+	_, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/positions/"+url.PathEscape(symbolOrAssetID), nil)
+	if err != nil {
+		return position, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &position); err != nil {
+		return position, fmt.Errorf("error parsing position: %v", err)
+	}
+	return position, nil
+}
+
+// ClosePosition liquidates qty shares or contracts, or percentage (0-100) of the position.
+// Leave both at 0 to close the whole position.
+func ClosePosition(symbolOrAssetID string, qty float64, percentage float64) (Order, error) {
+	return defaultBroker.ClosePosition(symbolOrAssetID, qty, percentage)
+}
+
+func (b APIBroker) ClosePosition(symbolOrAssetID string, qty float64, percentage float64) (Order, error) {
+	var order Order
+
+	if qty > 0 && percentage > 0 {
+		return order, fmt.Errorf("set at most one of qty and percentage")
+	}
+	if percentage > 100 {
+		return order, fmt.Errorf("percentage %v exceeds 100", percentage)
+	}
+
+	params := url.Values{}
+	if qty > 0 {
+		params.Set("qty", strconv.FormatFloat(qty, 'f', -1, 64))
+	}
+	if percentage > 0 {
+		params.Set("percentage", strconv.FormatFloat(percentage, 'f', -1, 64))
+	}
+
+	_, bodyStr, err := b.APIRequestMethod("DELETE", buildURL(b.tradingURL(), "/v2/positions/"+url.PathEscape(symbolOrAssetID), params), nil)
+	if err != nil {
+		return order, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &order); err != nil {
+		return order, fmt.Errorf("error parsing order: %v", err)
+	}
+	return order, nil
+}
+
+// CloseAllPositions liquidates all positions, cancelling open orders first if cancelOrders is set
+func CloseAllPositions(cancelOrders bool) ([]CloseStatus, error) {
+	return defaultBroker.CloseAllPositions(cancelOrders)
+}
+
+func (b APIBroker) CloseAllPositions(cancelOrders bool) ([]CloseStatus, error) {
+	endpoint := b.tradingURL() + "/v2/positions"
+	if cancelOrders {
+		endpoint += "?cancel_orders=true"
+	}
+
+	_, bodyStr, err := b.APIRequestMethod("DELETE", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []CloseStatus
+	if bodyStr == "" {
+		return statuses, nil
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &statuses); err != nil {
+		return nil, fmt.Errorf("error parsing close statuses: %v", err)
+	}
+	return statuses, nil
+}
+
+// ExercisePosition exercises a held option contract, given by OCC symbol or contract ID
+func ExercisePosition(symbolOrContractID string) error {
+	return defaultBroker.ExercisePosition(symbolOrContractID)
+}
+
+func (b APIBroker) ExercisePosition(symbolOrContractID string) error {
+	_, _, err := b.APIRequestMethod("POST", b.tradingURL()+"/v2/positions/"+url.PathEscape(symbolOrContractID)+"/exercise", nil)
+	return err
+}
+
+// OptionPosition is an option position joined with its contract and market data
+type OptionPosition struct {
+	Position Position
+	Option   Option
+}
+
+// Exposure sums the Greeks and P&L of positions, Greeks are per one dollar or vol point move of
+// the underlying for the whole position (greek * qty * multiplier)
+type Exposure struct {
+	Delta        float64
+	Gamma        float64
+	Theta        float64
+	Vega         float64
+	Rho          float64
+	MarketValue  float64
+	CostBasis    float64
+	UnrealizedPL float64
+}
+
+// JoinPositions pairs option positions with the matching contracts from GetOptions. Positions
+// without a matching contract, e.g. equities, are returned separately.
+func JoinPositions(positions []Position, options []Option) ([]OptionPosition, []Position) {
+	bySymbol := make(map[string]Option, len(options))
+	for _, opt := range options {
+		bySymbol[opt.Symbol] = opt
+	}
+
+	var joined []OptionPosition
+	var unmatched []Position
+	for _, position := range positions {
+		opt, ok := bySymbol[position.Symbol]
+		if !ok {
+			unmatched = append(unmatched, position)
+			continue
+		}
+		joined = append(joined, OptionPosition{Position: position, Option: opt})
+	}
+	return joined, unmatched
+}
+
+// MarkPrice returns the quote mid, falling back to the last trade and the position's current price
+func (p OptionPosition) MarkPrice() float64 {
+	if q := p.Option.LatestQuote; q != nil && q.BidPrice > 0 && q.AskPrice > 0 {
+		return (q.BidPrice + q.AskPrice) / 2
+	}
+	if t := p.Option.LatestTrade; t != nil && t.Price > 0 {
+		return t.Price
+	}
+	return float64(p.Position.CurrentPrice)
+}
+
+// Exposure computes the position Greeks and P&L from the latest snapshot data. Short positions
+// carry a negative quantity, so their Greeks and market value are negative.
+func (p OptionPosition) Exposure() Exposure {
+	multiplier := float64(p.Option.Multiplier)
+	if multiplier == 0 {
+		multiplier = 100
+	}
+	units := float64(p.Position.Qty) * multiplier
+
+	e := Exposure{
+		MarketValue: p.MarkPrice() * units,
+		CostBasis:   float64(p.Position.CostBasis),
+	}
+	e.UnrealizedPL = e.MarketValue - e.CostBasis
+
+	if g := p.Option.Greeks; g != nil {
+		e.Delta = g.Delta * units
+		e.Gamma = g.Gamma * units
+		e.Theta = g.Theta * units
+		e.Vega = g.Vega * units
+		e.Rho = g.Rho * units
+	}
+	return e
+}
+
+// TotalExposure sums the exposure of all option positions
+func TotalExposure(positions []OptionPosition) Exposure {
+	var total Exposure
+	for _, p := range positions {
+		e := p.Exposure()
+		total.Delta += e.Delta
+		total.Gamma += e.Gamma
+		total.Theta += e.Theta
+		total.Vega += e.Vega
+		total.Rho += e.Rho
+		total.MarketValue += e.MarketValue
+		total.CostBasis += e.CostBasis
+		total.UnrealizedPL += e.UnrealizedPL
+	}
+	return total
+}
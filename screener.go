@@ -0,0 +1,135 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ActiveStock represents one entry of the most actives screener
+type ActiveStock struct {
+	Symbol     string  `json:"symbol"`
+	Volume     float64 `json:"volume"`
+	TradeCount float64 `json:"trade_count"`
+}
+
+type MostActives struct {
+	MostActives []ActiveStock `json:"most_actives"`
+	LastUpdated time.Time     `json:"last_updated"`
+}
+
+// Mover represents one gainer or loser of the movers screener
+type Mover struct {
+	Symbol        string  `json:"symbol"`
+	PercentChange float64 `json:"percent_change"`
+	Change        float64 `json:"change"`
+	Price         float64 `json:"price"`
+}
+
+type Movers struct {
+	Gainers     []Mover   `json:"gainers"`
+	Losers      []Mover   `json:"losers"`
+	MarketType  string    `json:"market_type"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// GetMostActives returns the top stocks by "volume" or by "trades", a top of 0 returns the
+// API's default number
+func GetMostActives(by string, top int) (MostActives, error) {
+	return defaultBroker.GetMostActives(by, top)
+}
+
+func (b APIBroker) GetMostActives(by string, top int) (MostActives, error) {
+	var actives MostActives
+
+	if by != "volume" && by != "trades" {
+		return actives, fmt.Errorf("invalid ranking %q. Expected volume or trades", by)
+	}
+
+	params := url.Values{}
+	params.Set("by", by)
+	if top > 0 {
+		params.Set("top", strconv.Itoa(top))
+	}
+
+	_, bodyStr, err := b.APIRequest(buildURL(b.dataURL(), "/v1beta1/screener/stocks/most-actives", params), 1)
+	if err != nil {
+		return actives, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &actives); err != nil {
+		return actives, fmt.Errorf("error parsing most actives: %v", err)
+	}
+
+	return actives, nil
+}
+
+// GetMovers returns the top gainers and losers of a market type ("stocks" or "crypto"), a top
+// of 0 returns the API's default number
+func GetMovers(marketType string, top int) (Movers, error) {
+	return defaultBroker.GetMovers(marketType, top)
+}
+
+func (b APIBroker) GetMovers(marketType string, top int) (Movers, error) {
+	var movers Movers
+
+	if marketType != "stocks" && marketType != "crypto" {
+		return movers, fmt.Errorf("invalid market type %q. Expected stocks or crypto", marketType)
+	}
+
+	params := url.Values{}
+	if top > 0 {
+		params.Set("top", strconv.Itoa(top))
+	}
+
+	_, bodyStr, err := b.APIRequest(buildURL(b.dataURL(), "/v1beta1/screener/"+marketType+"/movers", params), 1)
+	if err != nil {
+		return movers, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &movers); err != nil {
+		return movers, fmt.Errorf("error parsing movers: %v", err)
+	}
+
+	return movers, nil
+}
+
+// Symbols returns the symbols of the most active stocks, most active first
+func (m MostActives) Symbols() []string {
+	var symbols []string
+	for _, stock := range m.MostActives {
+		symbols = append(symbols, stock.Symbol)
+	}
+	return symbols
+}
+
+// Symbols returns the gainers followed by the losers
+func (m Movers) Symbols() []string {
+	var symbols []string
+	for _, mover := range m.Gainers {
+		symbols = append(symbols, mover.Symbol)
+	}
+	for _, mover := range m.Losers {
+		symbols = append(symbols, mover.Symbol)
+	}
+	return symbols
+}
+
+// OptionRequests builds one option request per symbol from template, ready for MergeRequests
+func OptionRequests(symbols []string, template OptionURLReq) []OptionURLReq {
+	seen := make(map[string]bool)
+	var optreqs []OptionURLReq
+	for _, symbol := range symbols {
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+
+		optreq := template
+		optreq.Ticker = symbol
+		optreq.StrikeRange = append([]int(nil), template.StrikeRange...)
+		optreq.DateRange = append([]string(nil), template.DateRange...)
+		optreqs = append(optreqs, optreq)
+	}
+	return optreqs
+}
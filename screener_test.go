@@ -0,0 +1,63 @@
+package alpacaApiClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestScreenerTop(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		switch r.URL.Path {
+		case "/v1beta1/screener/stocks/most-actives":
+			w.Write([]byte(`{"most_actives":[{"symbol":"NVDA","volume":1200},{"symbol":"AAPL","volume":800}],"last_updated":"2025-01-10T15:00:00Z"}`))
+		case "/v1beta1/screener/stocks/movers":
+			w.Write([]byte(`{"gainers":[{"symbol":"XYZ","percent_change":12.5}],"losers":[{"symbol":"ABC","percent_change":-8}],"market_type":"stocks"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	b := APIBroker{DataURL: srv.URL, APIKeyID: "key", APISecretKey: "secret", Client: srv.Client()}
+
+	for _, tc := range []struct {
+		top  int
+		want string
+	}{
+		{0, "by=volume"},
+		{5, "by=volume&top=5"},
+	} {
+		actives, err := b.GetMostActives("volume", tc.top)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != tc.want {
+			t.Errorf("most actives query %q with top %d, want %q", query, tc.top, tc.want)
+		}
+		if symbols := actives.Symbols(); !reflect.DeepEqual(symbols, []string{"NVDA", "AAPL"}) {
+			t.Errorf("most active symbols %v", symbols)
+		}
+	}
+
+	for _, tc := range []struct {
+		top  int
+		want string
+	}{
+		{0, ""},
+		{10, "top=10"},
+	} {
+		movers, err := b.GetMovers("stocks", tc.top)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != tc.want {
+			t.Errorf("movers query %q with top %d, want %q", query, tc.top, tc.want)
+		}
+		if symbols := movers.Symbols(); !reflect.DeepEqual(symbols, []string{"XYZ", "ABC"}) {
+			t.Errorf("mover symbols %v", symbols)
+		}
+	}
+}
@@ -0,0 +1,982 @@
+package alpacaApiClient
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Simulator is a local broker filling orders against supplied quotes and bars instead of the
+// Alpaca API. Its order, position and account methods mirror the package functions of the same
+// name. Time only advances with the data: quotes and bars move the clock forward, expiring day
+// orders at the close and settling option positions at expiration.
+//
+// Supported are simple market, limit, stop and stop limit orders with time in force day, gtc,
+// opg, cls, ioc and fok.
+type Simulator struct {
+	// Adverse price move applied to market and triggered stop fills, as fraction of the price
+	Slippage float64
+
+	// Commission charged per fill, at least MinCommission
+	CommissionPerShare    float64
+	CommissionPerContract float64
+	MinCommission         float64
+
+	// Fraction of the quote size or bar volume one match may fill, 0 to always fill completely
+	FillRatio float64
+
+	mutex      sync.Mutex
+	now        time.Time
+	cash       float64
+	seq        int
+	orders     []*simOrder
+	positions  map[string]*simPosition
+	quotes     map[string]Quote
+	lastPrices map[string]float64
+	contracts  map[string]Option
+	activities []Activity
+}
+
+type simOrder struct {
+	Order
+	triggered bool
+	expiresAt time.Time
+}
+
+type simPosition struct {
+	assetID  string
+	qty      float64
+	avgEntry float64
+}
+
+func NewSimulator(cash float64, start time.Time) *Simulator {
+	return &Simulator{
+		now:        start,
+		cash:       cash,
+		positions:  make(map[string]*simPosition),
+		quotes:     make(map[string]Quote),
+		lastPrices: make(map[string]float64),
+		contracts:  make(map[string]Option),
+	}
+}
+
+// Now returns the simulation time
+func (s *Simulator) Now() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.now
+}
+
+// AddContracts registers option contracts for their multiplier, underlying and expiration and
+// takes their latest quotes. Contracts not registered are read from the OCC symbol.
+func (s *Simulator) AddContracts(options ...Option) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, o := range options {
+		s.contracts[o.Symbol] = o
+		if o.LatestQuote != nil && o.LatestQuote.AskPrice > 0 {
+			s.quotes[o.Symbol] = *o.LatestQuote
+		}
+	}
+}
+
+// SetQuote updates the quote of a symbol, advances the clock to its timestamp and matches the
+// open orders of the symbol against it
+func (s *Simulator) SetQuote(symbol string, quote Quote) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.advance(quote.Timestamp)
+	s.quotes[symbol] = quote
+	for _, order := range s.openOrders(symbol) {
+		s.matchQuote(order, quote)
+	}
+	return err
+}
+
+// ApplyBar advances the clock to the bar and matches the open orders of symbol against its range.
+// Market orders fill at the open, limit and stop orders at their price or a better open.
+func (s *Simulator) ApplyBar(symbol string, bar Bar) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.advance(bar.Timestamp)
+	for _, order := range s.openOrders(symbol) {
+		s.matchBar(order, bar)
+	}
+	s.lastPrices[symbol] = bar.Close
+	delete(s.quotes, symbol)
+	return err
+}
+
+// AdvanceTo moves the clock forward, expiring orders and settling expired option positions
+func (s *Simulator) AdvanceTo(t time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.advance(t)
+}
+
+func (s *Simulator) advance(t time.Time) error {
+	if !t.After(s.now) {
+		return nil
+	}
+	s.now = t
+
+	for _, order := range s.orders {
+		if isOpenStatus(order.Status) && !order.expiresAt.IsZero() && !s.now.Before(order.expiresAt) {
+			s.close(order, "expired")
+		}
+	}
+	return s.settleExpirations()
+}
+
+func (s *Simulator) nextID() string {
+	s.seq++
+	return fmt.Sprintf("sim-%08d", s.seq)
+}
+
+// sessionClose returns the 16:00 New York close at or after t
+func sessionClose(t time.Time) time.Time {
+	t = t.In(Market)
+	close := time.Date(t.Year(), t.Month(), t.Day(), 16, 0, 0, 0, Market)
+	if !t.Before(close) {
+		close = close.AddDate(0, 0, 1)
+	}
+	return close
+}
+
+func isOpenStatus(status string) bool {
+	switch status {
+	case "new", "accepted", "pending_new", "partially_filled":
+		return true
+	}
+	return false
+}
+
+func (s *Simulator) openOrders(symbol string) []*simOrder {
+	var open []*simOrder
+	for _, order := range s.orders {
+		if order.Symbol == symbol && isOpenStatus(order.Status) {
+			open = append(open, order)
+		}
+	}
+	return open
+}
+
+var occPattern = regexp.MustCompile(`^([A-Z0-9]{1,6})(\d{6})([CP])(\d{8})$`)
+
+// contract returns the registered contract of an option symbol or one read from the OCC symbol
+func (s *Simulator) contract(symbol string) (Option, bool) {
+	if o, ok := s.contracts[symbol]; ok {
+		return o, true
+	}
+	m := occPattern.FindStringSubmatch(symbol)
+	if m == nil {
+		return Option{}, false
+	}
+	expiry, err := time.Parse("060102", m[2])
+	if err != nil {
+		return Option{}, false
+	}
+	strike, _ := strconv.Atoi(m[4])
+	o := Option{
+		Symbol:           symbol,
+		RootSymbol:       m[1],
+		UnderlyingSymbol: m[1],
+		ExpirationDate:   expiry.Format("2006-01-02"),
+		Type:             "call",
+		StrikePrice:      float64(strike) / 1000,
+		Multiplier:       100,
+	}
+	if m[3] == "P" {
+		o.Type = "put"
+	}
+	return o, true
+}
+
+func (s *Simulator) multiplier(symbol string) float64 {
+	if o, ok := s.contract(symbol); ok {
+		if o.Multiplier > 0 {
+			return float64(o.Multiplier)
+		}
+		return 100
+	}
+	return 1
+}
+
+func (s *Simulator) assetClass(symbol string) string {
+	if _, ok := s.contract(symbol); ok {
+		return "us_option"
+	}
+	return "us_equity"
+}
+
+// markPrice returns the quote mid, the last bar close or 0 if the symbol has no data
+func (s *Simulator) markPrice(symbol string) float64 {
+	if q, ok := s.quotes[symbol]; ok && q.BidPrice > 0 && q.AskPrice > 0 {
+		return (q.BidPrice + q.AskPrice) / 2
+	}
+	return s.lastPrices[symbol]
+}
+
+// SubmitOrder validates the order and matches it against the latest quote of its symbol
+func (s *Simulator) SubmitOrder(orderreq OrderReq) (Order, error) {
+	if err := orderreq.Validate(); err != nil {
+		return Order{}, err
+	}
+	if orderreq.OrderClass != "" && orderreq.OrderClass != OrderClassSimple {
+		return Order{}, fmt.Errorf("the simulator does not support %s orders", orderreq.OrderClass)
+	}
+	if orderreq.Type == OrderTypeTrailingStop {
+		return Order{}, fmt.Errorf("the simulator does not support trailing stop orders")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if orderreq.ClientOrderID != "" {
+		for _, order := range s.orders {
+			if order.ClientOrderID == orderreq.ClientOrderID {
+				return Order{}, fmt.Errorf("client order ID %s is already in use", orderreq.ClientOrderID)
+			}
+		}
+	}
+
+	qty := orderreq.Qty
+	price := s.markPrice(orderreq.Symbol)
+	if orderreq.Notional > 0 {
+		if price <= 0 {
+			return Order{}, fmt.Errorf("no price for %s to size the notional order", orderreq.Symbol)
+		}
+		qty = orderreq.Notional / price
+	}
+	if orderreq.Type == OrderTypeLimit || orderreq.Type == OrderTypeStopLimit {
+		price = orderreq.LimitPrice
+	}
+	if orderreq.Side == SideBuy && price > 0 && qty*price*s.multiplier(orderreq.Symbol) > s.cash {
+		return Order{}, fmt.Errorf("insufficient buying power for %v %s", qty, orderreq.Symbol)
+	}
+
+	order := s.newOrder(orderreq, qty)
+	if quote, ok := s.quotes[orderreq.Symbol]; ok {
+		s.matchQuote(order, quote)
+	}
+	if order.TimeInForce == TimeInForceIOC || order.TimeInForce == TimeInForceFOK {
+		if isOpenStatus(order.Status) {
+			s.close(order, "canceled")
+		}
+	}
+	return order.Order, nil
+}
+
+func (s *Simulator) newOrder(orderreq OrderReq, qty float64) *simOrder {
+	clientOrderID := orderreq.ClientOrderID
+	id := s.nextID()
+	if clientOrderID == "" {
+		clientOrderID = id
+	}
+	order := &simOrder{Order: Order{
+		ID:             id,
+		ClientOrderID:  clientOrderID,
+		CreatedAt:      s.now,
+		UpdatedAt:      s.now,
+		SubmittedAt:    s.now,
+		Symbol:         orderreq.Symbol,
+		AssetClass:     s.assetClass(orderreq.Symbol),
+		Notional:       Decimal(orderreq.Notional),
+		Qty:            Decimal(qty),
+		OrderClass:     OrderClassSimple,
+		Type:           orderreq.Type,
+		Side:           orderreq.Side,
+		PositionIntent: orderreq.PositionIntent,
+		TimeInForce:    orderreq.TimeInForce,
+		LimitPrice:     Decimal(orderreq.LimitPrice),
+		StopPrice:      Decimal(orderreq.StopPrice),
+		Status:         "new",
+		ExtendedHours:  orderreq.ExtendedHours,
+	}}
+	switch order.TimeInForce {
+	case TimeInForceDay, TimeInForceOPG, TimeInForceCLS:
+		order.expiresAt = sessionClose(s.now)
+	}
+	s.orders = append(s.orders, order)
+	return order
+}
+
+// fillPrice returns the price an order executes at given a reference price, false if the
+// order does not execute at that price
+func (s *Simulator) fillPrice(order *simOrder, reference float64) (float64, bool) {
+	buy := order.Side == SideBuy
+	if !order.triggered && (order.Type == OrderTypeStop || order.Type == OrderTypeStopLimit) {
+		stop := float64(order.StopPrice)
+		if (buy && reference < stop) || (!buy && reference > stop) {
+			return 0, false
+		}
+		order.triggered = true
+	}
+
+	switch order.Type {
+	case OrderTypeMarket, OrderTypeStop:
+		if buy {
+			return reference * (1 + s.Slippage), true
+		}
+		return reference * (1 - s.Slippage), true
+	default:
+		limit := float64(order.LimitPrice)
+		if buy && reference <= limit {
+			return reference, true
+		}
+		if !buy && reference >= limit {
+			return reference, true
+		}
+	}
+	return 0, false
+}
+
+// matchQuote fills an order against the ask (buys) or bid (sells) of a quote
+func (s *Simulator) matchQuote(order *simOrder, quote Quote) {
+	reference, size := quote.AskPrice, float64(quote.AskSize)
+	if order.Side == SideSell {
+		reference, size = quote.BidPrice, float64(quote.BidSize)
+	}
+	if reference <= 0 {
+		return
+	}
+	if price, ok := s.fillPrice(order, reference); ok {
+		s.execute(order, price, size)
+	}
+}
+
+// matchBar fills an order against the range of a bar. Orders executable at the open fill there,
+// stops triggered within the bar at the stop price and limits reached within the bar at the limit.
+func (s *Simulator) matchBar(order *simOrder, bar Bar) {
+	buy := order.Side == SideBuy
+	reference, extreme := bar.Open, bar.High
+	if !buy {
+		extreme = bar.Low
+	}
+	if !order.triggered && (order.Type == OrderTypeStop || order.Type == OrderTypeStopLimit) {
+		stop := float64(order.StopPrice)
+		if (buy && bar.Open < stop) || (!buy && bar.Open > stop) {
+			if (buy && extreme < stop) || (!buy && extreme > stop) {
+				return
+			}
+			reference = stop
+		}
+		order.triggered = true
+	}
+
+	price, ok := s.fillPrice(order, reference)
+	if !ok && (order.Type == OrderTypeLimit || order.Type == OrderTypeStopLimit) {
+		limit := float64(order.LimitPrice)
+		if (buy && bar.Low <= limit) || (!buy && bar.High >= limit) {
+			price, ok = limit, true
+		}
+	}
+	if !ok {
+		return
+	}
+	s.execute(order, price, float64(bar.Volume))
+}
+
+// execute fills as much of an order as the available size and FillRatio allow
+func (s *Simulator) execute(order *simOrder, price float64, available float64) {
+	remaining := float64(order.Qty - order.FilledQty)
+	qty := remaining
+	if s.FillRatio > 0 {
+		qty = math.Min(remaining, math.Floor(available*s.FillRatio))
+	}
+	if order.TimeInForce == TimeInForceFOK && qty < remaining {
+		s.close(order, "canceled")
+		return
+	}
+	if qty <= 0 {
+		return
+	}
+
+	s.book(order.Symbol, order.Side, qty, price)
+	commission := s.commission(order.Symbol, qty)
+	s.cash -= commission
+
+	filled := float64(order.FilledQty)
+	order.FilledAvgPrice = Decimal((float64(order.FilledAvgPrice)*filled + price*qty) / (filled + qty))
+	order.FilledQty += Decimal(qty)
+	order.UpdatedAt = s.now
+	order.Status = "partially_filled"
+	fillType := "partial_fill"
+	if order.FilledQty >= order.Qty {
+		order.Status = "filled"
+		order.FilledAt = s.now
+		fillType = "fill"
+	}
+
+	s.activities = append(s.activities, TradeActivity{
+		ID:              s.nextID(),
+		Type:            ActivityFill,
+		TransactionTime: s.now,
+		FillType:        fillType,
+		Price:           Decimal(price),
+		Qty:             Decimal(qty),
+		Side:            order.Side,
+		Symbol:          order.Symbol,
+		LeavesQty:       order.Qty - order.FilledQty,
+		OrderID:         order.ID,
+		CumQty:          order.FilledQty,
+		OrderStatus:     order.Status,
+	})
+	if commission > 0 {
+		s.addActivity(ActivityFee, order.Symbol, 0, -commission, "commission")
+	}
+}
+
+func (s *Simulator) commission(symbol string, qty float64) float64 {
+	perUnit := s.CommissionPerShare
+	if s.assetClass(symbol) == "us_option" {
+		perUnit = s.CommissionPerContract
+	}
+	commission := perUnit * qty
+	if commission > 0 && commission < s.MinCommission {
+		commission = s.MinCommission
+	}
+	return commission
+}
+
+func (s *Simulator) addActivity(activityType string, symbol string, qty float64, amount float64, description string) {
+	s.activities = append(s.activities, NonTradeActivity{
+		ID:          s.nextID(),
+		Type:        activityType,
+		Date:        s.now.In(Market).Format("2006-01-02"),
+		NetAmount:   Decimal(amount),
+		Symbol:      symbol,
+		Qty:         Decimal(qty),
+		Description: description,
+		Status:      "executed",
+	})
+}
+
+// book applies a trade to the position and the cash balance
+func (s *Simulator) book(symbol string, side string, qty float64, price float64) {
+	signed := qty
+	if side == SideSell {
+		signed = -qty
+	}
+	s.cash -= signed * price * s.multiplier(symbol)
+
+	position, ok := s.positions[symbol]
+	if !ok {
+		position = &simPosition{assetID: s.nextID()}
+		s.positions[symbol] = position
+	}
+	held := position.qty
+	switch {
+	case held == 0 || (held > 0) == (signed > 0):
+		position.avgEntry = (position.avgEntry*math.Abs(held) + price*qty) / (math.Abs(held) + qty)
+	case math.Abs(signed) > math.Abs(held):
+		position.avgEntry = price
+	}
+	position.qty = held + signed
+	if math.Abs(position.qty) < 1e-9 {
+		delete(s.positions, symbol)
+	}
+}
+
+func (s *Simulator) close(order *simOrder, status string) {
+	order.Status = status
+	order.UpdatedAt = s.now
+	if status == "expired" {
+		order.ExpiredAt = s.now
+	} else {
+		order.CanceledAt = s.now
+	}
+}
+
+// settleExpirations exercises, assigns or expires the option positions expired by now. Contracts
+// in the money by at least $0.01 at the underlying mark are exercised (long) or assigned (short).
+func (s *Simulator) settleExpirations() error {
+	var symbols []string
+	for symbol := range s.positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var missing []string
+	for _, symbol := range symbols {
+		o, ok := s.contract(symbol)
+		if !ok {
+			continue
+		}
+		expiry, err := time.ParseInLocation("2006-01-02", o.ExpirationDate, Market)
+		if err != nil || s.now.Before(expiry.Add(16*time.Hour)) {
+			continue
+		}
+
+		for _, order := range s.openOrders(symbol) {
+			s.close(order, "expired")
+		}
+
+		underlying := s.markPrice(o.UnderlyingSymbol)
+		if underlying <= 0 {
+			missing = append(missing, symbol)
+			continue
+		}
+		intrinsic := underlying - o.StrikePrice
+		if o.Type == "put" {
+			intrinsic = -intrinsic
+		}
+
+		if intrinsic < 0.01 {
+			s.addActivity(ActivityOptExpire, symbol, s.positions[symbol].qty, 0, "expired worthless")
+			delete(s.positions, symbol)
+			continue
+		}
+		s.exercise(symbol, o)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("no underlying price to settle expired options %v", missing)
+	}
+	return nil
+}
+
+// exercise settles an option position into shares of the underlying at the strike. Long calls
+// and short puts receive shares, long puts and short calls deliver them.
+func (s *Simulator) exercise(symbol string, o Option) {
+	qty := s.positions[symbol].qty
+	delete(s.positions, symbol)
+
+	shares := math.Abs(qty) * s.multiplier(symbol)
+	side := SideBuy
+	if (o.Type == "call") != (qty > 0) {
+		side = SideSell
+	}
+	s.book(o.UnderlyingSymbol, side, shares, o.StrikePrice)
+
+	activityType := ActivityOptExercise
+	if qty < 0 {
+		activityType = ActivityOptAssign
+	}
+	amount := shares * o.StrikePrice
+	if side == SideBuy {
+		amount = -amount
+	}
+	s.addActivity(activityType, symbol, qty, amount, fmt.Sprintf("%s %v %s at %v", side, shares, o.UnderlyingSymbol, o.StrikePrice))
+}
+
+// ExercisePosition exercises a long option position right away, in or out of the money
+func (s *Simulator) ExercisePosition(symbolOrContractID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for symbol, p := range s.positions {
+		if symbol != symbolOrContractID && p.assetID != symbolOrContractID {
+			continue
+		}
+		o, ok := s.contract(symbol)
+		if !ok {
+			return fmt.Errorf("%s is not an option position", symbol)
+		}
+		if p.qty <= 0 {
+			return fmt.Errorf("only long option positions can be exercised")
+		}
+		for _, order := range s.openOrders(symbol) {
+			s.close(order, "canceled")
+		}
+		s.exercise(symbol, o)
+		return nil
+	}
+	return fmt.Errorf("position %s does not exist", symbolOrContractID)
+}
+
+func (s *Simulator) findOrder(orderID string) (*simOrder, error) {
+	for _, order := range s.orders {
+		if order.ID == orderID {
+			return order, nil
+		}
+	}
+	return nil, fmt.Errorf("order %s not found", orderID)
+}
+
+func (s *Simulator) GetOrder(orderID string) (Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	order, err := s.findOrder(orderID)
+	if err != nil {
+		return Order{}, err
+	}
+	return order.Order, nil
+}
+
+func (s *Simulator) GetOrderByClientID(clientOrderID string) (Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, order := range s.orders {
+		if order.ClientOrderID == clientOrderID {
+			return order.Order, nil
+		}
+	}
+	return Order{}, fmt.Errorf("order with client order ID %s not found", clientOrderID)
+}
+
+// ListOrders returns the orders matching the request, like ListOrders
+func (s *Simulator) ListOrders(listreq ListOrdersReq) ([]Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := listreq.Status
+	if status == "" {
+		status = "open"
+	}
+	var orders []Order
+	for _, order := range s.orders {
+		open := isOpenStatus(order.Status)
+		if (status == "open" && !open) || (status == "closed" && open) {
+			continue
+		}
+		if !listreq.After.IsZero() && !order.SubmittedAt.After(listreq.After) {
+			continue
+		}
+		if !listreq.Until.IsZero() && !order.SubmittedAt.Before(listreq.Until) {
+			continue
+		}
+		if len(listreq.Symbols) > 0 && !containsString(listreq.Symbols, order.Symbol) {
+			continue
+		}
+		if listreq.Side != "" && listreq.Side != order.Side {
+			continue
+		}
+		orders = append(orders, order.Order)
+	}
+
+	// Orders are kept in submission order
+	if listreq.Direction != "asc" {
+		for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+			orders[i], orders[j] = orders[j], orders[i]
+		}
+	}
+	if listreq.Limit > 0 && len(orders) > listreq.Limit {
+		orders = orders[:listreq.Limit]
+	}
+	return orders, nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceOrder replaces an open, unfilled order with a new order
+func (s *Simulator) ReplaceOrder(orderID string, replacereq ReplaceOrderReq) (Order, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	old, err := s.findOrder(orderID)
+	if err != nil {
+		return Order{}, err
+	}
+	if !isOpenStatus(old.Status) || old.FilledQty > 0 {
+		return Order{}, fmt.Errorf("order %s is not replaceable, status %s", orderID, old.Status)
+	}
+
+	orderreq := OrderReq{
+		Symbol:         old.Symbol,
+		Qty:            float64(old.Qty),
+		Side:           old.Side,
+		Type:           old.Type,
+		TimeInForce:    old.TimeInForce,
+		LimitPrice:     float64(old.LimitPrice),
+		StopPrice:      float64(old.StopPrice),
+		ExtendedHours:  old.ExtendedHours,
+		ClientOrderID:  replacereq.ClientOrderID,
+		PositionIntent: old.PositionIntent,
+	}
+	if replacereq.Qty > 0 {
+		orderreq.Qty = replacereq.Qty
+	}
+	if replacereq.TimeInForce != "" {
+		orderreq.TimeInForce = replacereq.TimeInForce
+	}
+	if replacereq.LimitPrice > 0 {
+		orderreq.LimitPrice = replacereq.LimitPrice
+	}
+	if replacereq.StopPrice > 0 {
+		orderreq.StopPrice = replacereq.StopPrice
+	}
+	if err := orderreq.Validate(); err != nil {
+		return Order{}, err
+	}
+
+	s.close(old, "replaced")
+	old.ReplacedAt = s.now
+	order := s.newOrder(orderreq, orderreq.Qty)
+	order.Replaces = old.ID
+	old.ReplacedBy = order.ID
+	if quote, ok := s.quotes[order.Symbol]; ok {
+		s.matchQuote(order, quote)
+	}
+	return order.Order, nil
+}
+
+func (s *Simulator) CancelOrder(orderID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order, err := s.findOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if !isOpenStatus(order.Status) {
+		return fmt.Errorf("order %s is already %s", orderID, order.Status)
+	}
+	s.close(order, "canceled")
+	return nil
+}
+
+func (s *Simulator) CancelAllOrders() ([]CancelStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var statuses []CancelStatus
+	for _, order := range s.orders {
+		if isOpenStatus(order.Status) {
+			s.close(order, "canceled")
+			statuses = append(statuses, CancelStatus{ID: order.ID, Status: 200, Body: order.Order})
+		}
+	}
+	return statuses, nil
+}
+
+// position returns the API view of a position valued at the mark price
+func (s *Simulator) position(symbol string, p *simPosition) Position {
+	price := s.markPrice(symbol)
+	if price <= 0 {
+		price = p.avgEntry
+	}
+	multiplier := s.multiplier(symbol)
+	side := "long"
+	if p.qty < 0 {
+		side = "short"
+	}
+	position := Position{
+		AssetID:       p.assetID,
+		Symbol:        symbol,
+		AssetClass:    s.assetClass(symbol),
+		AvgEntryPrice: Decimal(p.avgEntry),
+		Qty:           Decimal(p.qty),
+		QtyAvailable:  Decimal(p.qty),
+		Side:          side,
+		MarketValue:   Decimal(p.qty * price * multiplier),
+		CostBasis:     Decimal(p.qty * p.avgEntry * multiplier),
+		CurrentPrice:  Decimal(price),
+	}
+	position.UnrealizedPL = position.MarketValue - position.CostBasis
+	if position.CostBasis != 0 {
+		position.UnrealizedPLPC = Decimal(float64(position.UnrealizedPL) / math.Abs(float64(position.CostBasis)))
+	}
+	return position
+}
+
+func (s *Simulator) ListPositions() ([]Position, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	positions := []Position{}
+	for symbol, p := range s.positions {
+		positions = append(positions, s.position(symbol, p))
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions, nil
+}
+
+func (s *Simulator) GetPosition(symbolOrAssetID string) (Position, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for symbol, p := range s.positions {
+		if symbol == symbolOrAssetID || p.assetID == symbolOrAssetID {
+			return s.position(symbol, p), nil
+		}
+	}
+	return Position{}, fmt.Errorf("position %s does not exist", symbolOrAssetID)
+}
+
+// ClosePosition submits a market order closing qty or percentage of a position, like ClosePosition
+func (s *Simulator) ClosePosition(symbolOrAssetID string, qty float64, percentage float64) (Order, error) {
+	position, err := s.GetPosition(symbolOrAssetID)
+	if err != nil {
+		return Order{}, err
+	}
+	if qty > 0 && percentage > 0 {
+		return Order{}, fmt.Errorf("set at most one of qty and percentage")
+	}
+
+	held := math.Abs(float64(position.Qty))
+	if percentage > 0 {
+		qty = held * percentage / 100
+	}
+	if qty <= 0 {
+		qty = held
+	}
+	if qty > held {
+		return Order{}, fmt.Errorf("qty %v exceeds the position of %v", qty, held)
+	}
+
+	side := SideSell
+	if position.Qty < 0 {
+		side = SideBuy
+	}
+	return s.SubmitOrder(OrderReq{Symbol: position.Symbol, Qty: qty, Side: side, Type: OrderTypeMarket, TimeInForce: TimeInForceDay})
+}
+
+func (s *Simulator) CloseAllPositions(cancelOrders bool) ([]CloseStatus, error) {
+	if cancelOrders {
+		s.CancelAllOrders()
+	}
+	positions, _ := s.ListPositions()
+
+	var statuses []CloseStatus
+	for _, position := range positions {
+		order, err := s.ClosePosition(position.Symbol, 0, 0)
+		if err != nil {
+			return statuses, err
+		}
+		statuses = append(statuses, CloseStatus{Symbol: position.Symbol, Status: 200, Body: order})
+	}
+	return statuses, nil
+}
+
+// GetAccount values the positions at their mark prices. Buying power is the cash balance.
+func (s *Simulator) GetAccount() (Account, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account := Account{
+		ID:                   "sim",
+		AccountNumber:        "SIM",
+		Status:               "ACTIVE",
+		Currency:             "USD",
+		Cash:                 Decimal(s.cash),
+		Multiplier:           1,
+		OptionsApprovedLevel: 3,
+		OptionsTradingLevel:  3,
+	}
+	for symbol, p := range s.positions {
+		value := s.position(symbol, p).MarketValue
+		if value > 0 {
+			account.LongMarketValue += value
+		} else {
+			account.ShortMarketValue += value
+		}
+	}
+	account.Equity = account.Cash + account.LongMarketValue + account.ShortMarketValue
+	account.PortfolioValue = account.Equity
+	account.BuyingPower = account.Cash
+	account.RegTBuyingPower = account.Cash
+	account.NonMarginableBuyingPower = account.Cash
+	account.OptionsBuyingPower = account.Cash
+	return account, nil
+}
+
+// GetAccountActivities returns the fills, commissions and option expirations, exercises and
+// assignments matching the request, like GetAccountActivities
+func (s *Simulator) GetAccountActivities(actreq ActivitiesReq) ([]Activity, error) {
+	if actreq.Date != "" && (!actreq.After.IsZero() || !actreq.Until.IsZero()) {
+		return nil, fmt.Errorf("Date cannot be combined with After or Until")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var activities []Activity
+	for _, activity := range s.activities {
+		t := activity.ActivityTime()
+		if len(actreq.Types) > 0 && !containsString(actreq.Types, activity.ActivityType()) {
+			continue
+		}
+		if actreq.Date != "" && t.In(Market).Format("2006-01-02") != actreq.Date {
+			continue
+		}
+		if !actreq.After.IsZero() && !t.After(actreq.After) {
+			continue
+		}
+		if !actreq.Until.IsZero() && t.After(actreq.Until) {
+			continue
+		}
+		activities = append(activities, activity)
+	}
+
+	if actreq.Direction != "asc" {
+		for i, j := 0, len(activities)-1; i < j; i, j = i+1, j-1 {
+			activities[i], activities[j] = activities[j], activities[i]
+		}
+	}
+	if actreq.PageToken != "" {
+		for i, activity := range activities {
+			if activity.ActivityID() == actreq.PageToken {
+				activities = activities[i+1:]
+				break
+			}
+		}
+	}
+	if actreq.Limit > 0 && len(activities) > actreq.Limit {
+		activities = activities[:actreq.Limit]
+	}
+	return activities, nil
+}
+
+// SingleQuote returns the ask price of the latest quote or the last bar close, like SingleQuote
+func (s *Simulator) SingleQuote(ticker string) (float64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if q, ok := s.quotes[ticker]; ok && q.AskPrice > 0 {
+		return q.AskPrice, nil
+	}
+	if price, ok := s.lastPrices[ticker]; ok {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no quote for %s", ticker)
+}
+
+// GetOptions returns the registered contracts matching the request with their latest quotes,
+// like GetOptions. nMax of 0 or -1 returns all of them.
+func (s *Simulator) GetOptions(optreq OptionURLReq, nMax int) ([]Option, string, error) {
+	if len(optreq.StrikeRange) != 2 || len(optreq.DateRange) != 2 {
+		return nil, "", fmt.Errorf("StrikeRange and DateRange need a lower and an upper bound")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var options []Option
+	for _, o := range s.contracts {
+		if o.UnderlyingSymbol != optreq.Ticker {
+			continue
+		}
+		if optreq.Contract_type != "" && o.Type != optreq.Contract_type {
+			continue
+		}
+		if o.StrikePrice < float64(optreq.StrikeRange[0]) || o.StrikePrice > float64(optreq.StrikeRange[1]) {
+			continue
+		}
+		if o.ExpirationDate < optreq.DateRange[0] || o.ExpirationDate > optreq.DateRange[1] {
+			continue
+		}
+		if q, ok := s.quotes[o.Symbol]; ok {
+			o.LatestQuote = &q
+		}
+		options = append(options, o)
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Symbol < options[j].Symbol })
+	if nMax > 0 && len(options) > nMax {
+		options = options[:nMax]
+	}
+	return options, "", nil
+}
@@ -0,0 +1,363 @@
+package alpacaApiClient
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Friday 10:00 in New York
+var simStart = time.Date(2025, 1, 17, 10, 0, 0, 0, Market)
+
+// simStep is market data fed to the simulator after the order was submitted
+type simStep struct {
+	quote *Quote
+	bar   *Bar
+}
+
+func quoteStep(bid, ask float64, size int) simStep {
+	return simStep{quote: &Quote{BidPrice: bid, AskPrice: ask, BidSize: size, AskSize: size}}
+}
+
+func barStep(open, high, low, close float64, volume int) simStep {
+	return simStep{bar: &Bar{Open: open, High: high, Low: low, Close: close, Volume: volume}}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestSimulatorFills(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(*Simulator)
+		quote *Quote // latest quote when the order is submitted
+		order OrderReq
+		steps []simStep
+
+		status string
+		filled float64
+		price  float64
+		cash   float64 // change of the cash balance
+	}{
+		{
+			name:   "MarketQuote",
+			setup:  func(s *Simulator) { s.Slippage = 0.01 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			status: "filled", filled: 10, price: 101, cash: -1010,
+		},
+		{
+			name:   "MarketSellQuote",
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideSell, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			status: "filled", filled: 10, price: 99, cash: 990,
+		},
+		{
+			name:   "MarketWithoutData",
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			status: "new",
+		},
+		{
+			name:   "LimitQuote",
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 98.5, TimeInForce: TimeInForceGTC},
+			steps:  []simStep{quoteStep(98.9, 99, 100), quoteStep(97.9, 98, 100)},
+			status: "filled", filled: 10, price: 98, cash: -980,
+		},
+		{
+			name:   "MarketBar",
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(100, 103, 99, 102, 1000)},
+			status: "filled", filled: 10, price: 100, cash: -1000,
+		},
+		{
+			name:   "LimitBarBetterOpen",
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 100, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(99, 101, 98, 100, 1000)},
+			status: "filled", filled: 10, price: 99, cash: -990,
+		},
+		{
+			name:   "LimitBarWithinRange",
+			order:  OrderReq{Qty: 10, Side: SideSell, Type: OrderTypeLimit, LimitPrice: 102, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(100, 101, 99, 100, 1000), barStep(100, 103, 99, 101, 1000)},
+			status: "filled", filled: 10, price: 102, cash: 1020,
+		},
+		{
+			name:   "StopQuote",
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeStop, StopPrice: 105, TimeInForce: TimeInForceDay},
+			steps:  []simStep{quoteStep(103, 104, 100), quoteStep(105, 106, 100)},
+			status: "filled", filled: 10, price: 106, cash: -1060,
+		},
+		{
+			name:   "StopBarWithinRange",
+			order:  OrderReq{Qty: 10, Side: SideSell, Type: OrderTypeStop, StopPrice: 95, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(97, 98, 96, 97, 1000), barStep(97, 98, 94, 95, 1000)},
+			status: "filled", filled: 10, price: 95, cash: 950,
+		},
+		{
+			name:   "StopBarGapOpen",
+			order:  OrderReq{Qty: 10, Side: SideSell, Type: OrderTypeStop, StopPrice: 95, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(93, 94, 92, 93, 1000)},
+			status: "filled", filled: 10, price: 93, cash: 930,
+		},
+		{
+			name:   "StopLimitBar",
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeStopLimit, StopPrice: 105, LimitPrice: 106, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(104, 108, 103, 107, 1000)},
+			status: "filled", filled: 10, price: 105, cash: -1050,
+		},
+		{
+			name:   "StopLimitTriggeredAboveLimit",
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeStopLimit, StopPrice: 105, LimitPrice: 105.5, TimeInForce: TimeInForceDay},
+			steps:  []simStep{quoteStep(106, 107, 100), quoteStep(105, 105.2, 100)},
+			status: "filled", filled: 10, price: 105.2, cash: -1052,
+		},
+		{
+			name:   "FillRatioPartial",
+			setup:  func(s *Simulator) { s.FillRatio = 0.5 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 8, AskSize: 8},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			status: "partially_filled", filled: 4, price: 100, cash: -400,
+		},
+		{
+			name:   "FillRatioCompleted",
+			setup:  func(s *Simulator) { s.FillRatio = 0.5 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 8, AskSize: 8},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			steps:  []simStep{quoteStep(100, 101, 20)},
+			status: "filled", filled: 10, price: 100.6, cash: -1006,
+		},
+		{
+			name:   "FillRatioBarVolume",
+			setup:  func(s *Simulator) { s.FillRatio = 0.1 },
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			steps:  []simStep{barStep(100, 101, 99, 100, 30)},
+			status: "partially_filled", filled: 3, price: 100, cash: -300,
+		},
+		{
+			name:   "IOCPartial",
+			setup:  func(s *Simulator) { s.FillRatio = 0.5 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 8, AskSize: 8},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceIOC},
+			status: "canceled", filled: 4, price: 100, cash: -400,
+		},
+		{
+			name:   "IOCWithoutQuote",
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceIOC},
+			steps:  []simStep{quoteStep(99, 100, 100)},
+			status: "canceled",
+		},
+		{
+			name:   "FOKTooSmall",
+			setup:  func(s *Simulator) { s.FillRatio = 0.5 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 8, AskSize: 8},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceFOK},
+			status: "canceled",
+		},
+		{
+			name:   "FOKFilled",
+			setup:  func(s *Simulator) { s.FillRatio = 0.5 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 20, AskSize: 20},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceFOK},
+			status: "filled", filled: 10, price: 100, cash: -1000,
+		},
+		{
+			name:   "CommissionMinimum",
+			setup:  func(s *Simulator) { s.CommissionPerShare, s.MinCommission = 0.005, 1 },
+			quote:  &Quote{BidPrice: 99, AskPrice: 100, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Qty: 10, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay},
+			status: "filled", filled: 10, price: 100, cash: -1001,
+		},
+		{
+			name:   "CommissionPerContract",
+			setup:  func(s *Simulator) { s.CommissionPerShare, s.CommissionPerContract, s.MinCommission = 0.005, 0.65, 1 },
+			quote:  &Quote{BidPrice: 2.4, AskPrice: 2.5, BidSize: 100, AskSize: 100},
+			order:  OrderReq{Symbol: "AAPL250117C00200000", Qty: 2, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay, PositionIntent: PositionIntentBuyToOpen},
+			status: "filled", filled: 2, price: 2.5, cash: -501.3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSimulator(100000, simStart)
+			if tc.setup != nil {
+				tc.setup(s)
+			}
+			symbol := tc.order.Symbol
+			if symbol == "" {
+				symbol = "AAPL"
+				tc.order.Symbol = symbol
+			}
+			now := simStart
+			if tc.quote != nil {
+				now = now.Add(time.Minute)
+				q := *tc.quote
+				q.Timestamp = now
+				s.SetQuote(symbol, q)
+			}
+
+			order, err := s.SubmitOrder(tc.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range tc.steps {
+				now = now.Add(time.Minute)
+				if step.quote != nil {
+					q := *step.quote
+					q.Timestamp = now
+					s.SetQuote(symbol, q)
+				} else {
+					b := *step.bar
+					b.Timestamp = now
+					s.ApplyBar(symbol, b)
+				}
+			}
+
+			order, err = s.GetOrder(order.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.Status != tc.status || !approx(float64(order.FilledQty), tc.filled) || !approx(float64(order.FilledAvgPrice), tc.price) {
+				t.Errorf("order %s filled %v at %v, want %s filled %v at %v", order.Status, order.FilledQty, order.FilledAvgPrice, tc.status, tc.filled, tc.price)
+			}
+			account, _ := s.GetAccount()
+			if !approx(float64(account.Cash)-100000, tc.cash) {
+				t.Errorf("cash changed by %v, want %v", float64(account.Cash)-100000, tc.cash)
+			}
+		})
+	}
+}
+
+func TestSimulatorDayOrderExpiry(t *testing.T) {
+	s := NewSimulator(100000, simStart)
+	day, err := s.SubmitOrder(OrderReq{Symbol: "AAPL", Qty: 1, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 90, TimeInForce: TimeInForceDay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtc, err := s.SubmitOrder(OrderReq{Symbol: "AAPL", Qty: 1, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 90, TimeInForce: TimeInForceGTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	close := time.Date(2025, 1, 17, 16, 0, 0, 0, Market)
+	s.AdvanceTo(close.Add(-time.Second))
+	if order, _ := s.GetOrder(day.ID); order.Status != "new" {
+		t.Errorf("day order %s before the close, want new", order.Status)
+	}
+	s.AdvanceTo(close)
+	if order, _ := s.GetOrder(day.ID); order.Status != "expired" || !order.ExpiredAt.Equal(close) {
+		t.Errorf("day order %s at %v after the close, want expired at %v", order.Status, order.ExpiredAt, close)
+	}
+	if order, _ := s.GetOrder(gtc.ID); order.Status != "new" {
+		t.Errorf("gtc order %s after the close, want new", order.Status)
+	}
+
+	// Day orders submitted after the close last until the next one
+	late, _ := s.SubmitOrder(OrderReq{Symbol: "AAPL", Qty: 1, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 90, TimeInForce: TimeInForceDay})
+	s.AdvanceTo(close.Add(23 * time.Hour))
+	if order, _ := s.GetOrder(late.ID); order.Status != "new" {
+		t.Errorf("late day order %s before the next close, want new", order.Status)
+	}
+	s.AdvanceTo(close.AddDate(0, 0, 1))
+	if order, _ := s.GetOrder(late.ID); order.Status != "expired" {
+		t.Errorf("late day order %s after the next close, want expired", order.Status)
+	}
+}
+
+func TestSimulatorExpiration(t *testing.T) {
+	const (
+		call = "AAPL250117C00200000"
+		put  = "AAPL250117P00200000"
+	)
+	for _, tc := range []struct {
+		name       string
+		symbol     string
+		side       string
+		underlying float64
+
+		activity string
+		shares   float64 // AAPL position after settlement
+		cash     float64 // change of the cash balance by the settlement
+	}{
+		{"LongCallITM", call, SideBuy, 210, ActivityOptExercise, 100, -20000},
+		{"ShortCallITM", call, SideSell, 210, ActivityOptAssign, -100, 20000},
+		{"LongPutITM", put, SideBuy, 190, ActivityOptExercise, -100, 20000},
+		{"ShortPutITM", put, SideSell, 190, ActivityOptAssign, 100, -20000},
+		{"LongCallOTM", call, SideBuy, 190, ActivityOptExpire, 0, 0},
+		{"ShortPutOTM", put, SideSell, 210, ActivityOptExpire, 0, 0},
+		{"LongCallAtTheMoney", call, SideBuy, 200.005, ActivityOptExpire, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSimulator(100000, simStart)
+			s.SetQuote(tc.symbol, Quote{BidPrice: 5, AskPrice: 5, BidSize: 10, AskSize: 10})
+			intent := PositionIntentBuyToOpen
+			if tc.side == SideSell {
+				intent = PositionIntentSellToOpen
+			}
+			if _, err := s.SubmitOrder(OrderReq{Symbol: tc.symbol, Qty: 1, Side: tc.side, Type: OrderTypeMarket, TimeInForce: TimeInForceGTC, PositionIntent: intent}); err != nil {
+				t.Fatal(err)
+			}
+			closing, err := s.SubmitOrder(OrderReq{Symbol: tc.symbol, Qty: 1, Side: SideBuy, Type: OrderTypeLimit, LimitPrice: 0.01, TimeInForce: TimeInForceGTC})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.SetQuote("AAPL", Quote{BidPrice: tc.underlying, AskPrice: tc.underlying, Timestamp: simStart.Add(time.Hour)})
+			before, _ := s.GetAccount()
+
+			close := time.Date(2025, 1, 17, 16, 0, 0, 0, Market)
+			if err := s.AdvanceTo(close.Add(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetPosition(tc.symbol); err != nil {
+				t.Fatalf("position settled before 16:00: %v", err)
+			}
+			if err := s.AdvanceTo(close); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.GetPosition(tc.symbol); err == nil {
+				t.Error("option position left after expiration")
+			}
+			shares := 0.0
+			if p, err := s.GetPosition("AAPL"); err == nil {
+				shares = float64(p.Qty)
+			}
+			if shares != tc.shares {
+				t.Errorf("%v AAPL shares, want %v", shares, tc.shares)
+			}
+			after, _ := s.GetAccount()
+			if !approx(float64(after.Cash-before.Cash), tc.cash) {
+				t.Errorf("cash changed by %v, want %v", after.Cash-before.Cash, tc.cash)
+			}
+			if order, _ := s.GetOrder(closing.ID); order.Status != "expired" {
+				t.Errorf("open order of the contract %s, want expired", order.Status)
+			}
+
+			activities, _ := s.GetAccountActivities(ActivitiesReq{Types: []string{tc.activity}})
+			if len(activities) != 1 {
+				t.Fatalf("%d %s activities, want 1", len(activities), tc.activity)
+			}
+			activity := activities[0].(NonTradeActivity)
+			if activity.Symbol != tc.symbol || activity.Date != "2025-01-17" {
+				t.Errorf("activity for %s on %s, want %s on 2025-01-17", activity.Symbol, activity.Date, tc.symbol)
+			}
+		})
+	}
+
+	t.Run("MissingUnderlying", func(t *testing.T) {
+		s := NewSimulator(100000, simStart)
+		s.SetQuote(call, Quote{BidPrice: 5, AskPrice: 5, BidSize: 10, AskSize: 10})
+		if _, err := s.SubmitOrder(OrderReq{Symbol: call, Qty: 1, Side: SideBuy, Type: OrderTypeMarket, TimeInForce: TimeInForceDay}); err != nil {
+			t.Fatal(err)
+		}
+		err := s.AdvanceTo(time.Date(2025, 1, 17, 16, 0, 0, 0, Market))
+		if err == nil || !strings.Contains(err.Error(), call) {
+			t.Errorf("expected an error naming %s, got %v", call, err)
+		}
+		if _, err := s.GetPosition(call); err != nil {
+			t.Error("position without an underlying price was settled")
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package alpacaApiClient
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Snappy block decompression for Parquet pages. Parquet stores raw blocks, without the framing
+// format: the uncompressed length as a varint followed by literal and copy elements.
+
+func snappyDecode(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > 1<<31 {
+		return nil, fmt.Errorf("invalid snappy length")
+	}
+	dst := make([]byte, 0, length)
+	for pos := n; pos < len(src); {
+		tag := src[pos]
+		pos++
+
+		if tag&0x03 == 0 {
+			// Literal, lengths from 61 on are stored in the 1 to 4 bytes after the tag
+			size := int(tag >> 2)
+			if size >= 60 {
+				k := size - 59
+				if pos+k > len(src) {
+					return nil, fmt.Errorf("truncated snappy literal")
+				}
+				size = 0
+				for i := k - 1; i >= 0; i-- {
+					size = size<<8 | int(src[pos+i])
+				}
+				pos += k
+			}
+			size++
+			if size > len(src)-pos || len(dst)+size > int(length) {
+				return nil, fmt.Errorf("snappy literal out of range")
+			}
+			dst = append(dst, src[pos:pos+size]...)
+			pos += size
+			continue
+		}
+
+		var size, offset int
+		switch tag & 0x03 {
+		case 1:
+			if pos >= len(src) {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size = 4 + int(tag>>2&0x07)
+			offset = int(tag&0xe0)<<3 | int(src[pos])
+			pos++
+		case 2:
+			if pos+2 > len(src) {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		case 3:
+			if pos+4 > len(src) {
+				return nil, fmt.Errorf("truncated snappy copy")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+		}
+		if offset <= 0 || offset > len(dst) || len(dst)+size > int(length) {
+			return nil, fmt.Errorf("snappy copy out of range")
+		}
+		// Byte by byte, the copy may overlap the bytes it appends
+		for i := 0; i < size; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if len(dst) != int(length) {
+		return nil, fmt.Errorf("snappy data has %d bytes, want %d", len(dst), length)
+	}
+	return dst, nil
+}
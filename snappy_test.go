@@ -0,0 +1,59 @@
+package alpacaApiClient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSnappyDecode(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789"), 10)
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "00", ""},
+		// Literal "abc" and an overlapping 1 byte offset copy of 9 bytes at offset 3
+		{"copy1", "0c" + "08616263" + "1503", "abcabcabcabc"},
+		// Literal "0123456789" and a 2 byte offset copy of 10 bytes at offset 10
+		{"copy2", "14" + "24" + hex.EncodeToString(long[:10]) + "260a00", string(long[:20])},
+		// Literal "abcd" and a 4 byte offset copy of 4 bytes at offset 4
+		{"copy4", "08" + "0c61626364" + "0f04000000", "abcdabcd"},
+		// A literal of 100 bytes with its length in the byte after the tag
+		{"long literal", "64" + "f063" + hex.EncodeToString(long), string(long)},
+	} {
+		src, err := hex.DecodeString(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := snappyDecode(src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: decoded %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSnappyDecodeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"copy before the start", "0c" + "08616263" + "1504", "out of range"},
+		{"copy past the length", "05" + "08616263" + "1503", "out of range"},
+		{"truncated literal", "04" + "0c6162", "out of range"},
+		{"truncated copy", "0c" + "08616263" + "26", "truncated"},
+		{"short output", "05" + "08616263", "3 bytes, want 5"},
+	} {
+		src, _ := hex.DecodeString(tc.src)
+		if _, err := snappyDecode(src); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Base URL of the real-time market data streams
+var DataStreamURL = "wss://stream.data.alpaca.markets"
+
+// Stock stream subscription channels. Corrections and trade cancels are sent automatically
+// for symbols subscribed to trades.
+const (
+	ChannelTrades      = "trades"
+	ChannelQuotes      = "quotes"
+	ChannelBars        = "bars"
+	ChannelUpdatedBars = "updatedBars"
+	ChannelDailyBars   = "dailyBars"
+	ChannelStatuses    = "statuses"
+	ChannelLULDs       = "lulds"
+)
+
+// StockSubscription lists symbols per channel, "*" subscribes to all symbols
+type StockSubscription struct {
+	Trades      []string
+	Quotes      []string
+	Bars        []string
+	UpdatedBars []string
+	DailyBars   []string
+	Statuses    []string
+	LULDs       []string
+}
+
+func (sub StockSubscription) channels() map[string][]string {
+	return map[string][]string{
+		ChannelTrades:      sub.Trades,
+		ChannelQuotes:      sub.Quotes,
+		ChannelBars:        sub.Bars,
+		ChannelUpdatedBars: sub.UpdatedBars,
+		ChannelDailyBars:   sub.DailyBars,
+		ChannelStatuses:    sub.Statuses,
+		ChannelLULDs:       sub.LULDs,
+	}
+}
+
+type StreamTrade struct {
+	Symbol     string
+	ID         int64
+	Tape       string
+	Conditions []string
+	Trade
+}
+
+type StreamQuote struct {
+	Symbol     string
+	Tape       string
+	Conditions []string
+	Quote
+}
+
+// StreamBar is a minute bar, an updated minute bar or a daily bar, see Channel
+type StreamBar struct {
+	Symbol  string
+	Channel string
+	Bar
+}
+
+type TradingStatus struct {
+	Symbol        string
+	StatusCode    string
+	StatusMessage string
+	ReasonCode    string
+	ReasonMessage string
+	Tape          string
+	Timestamp     time.Time
+}
+
+// LULD holds the limit up limit down price bands of a symbol
+type LULD struct {
+	Symbol    string
+	LimitUp   float64
+	LimitDown float64
+	Indicator string
+	Tape      string
+	Timestamp time.Time
+}
+
+type TradeCorrection struct {
+	Symbol              string
+	Exchange            string
+	Tape                string
+	OriginalID          int64
+	OriginalPrice       float64
+	OriginalSize        int
+	OriginalConditions  []string
+	CorrectedID         int64
+	CorrectedPrice      float64
+	CorrectedSize       int
+	CorrectedConditions []string
+	Timestamp           time.Time
+}
+
+// TradeCancel reports a cancelled (Action "C") or erroneous (Action "E") trade
+type TradeCancel struct {
+	Symbol    string
+	ID        int64
+	Exchange  string
+	Price     float64
+	Size      int
+	Action    string
+	Tape      string
+	Timestamp time.Time
+}
+
+func (e *StreamTrade) EventSymbol() string     { return e.Symbol }
+func (e *StreamQuote) EventSymbol() string     { return e.Symbol }
+func (e *StreamBar) EventSymbol() string       { return e.Symbol }
+func (e *TradingStatus) EventSymbol() string   { return e.Symbol }
+func (e *LULD) EventSymbol() string            { return e.Symbol }
+func (e *TradeCorrection) EventSymbol() string { return e.Symbol }
+func (e *TradeCancel) EventSymbol() string     { return e.Symbol }
+
+// StockStream streams real-time stock data of a feed (iex, sip or delayed_sip)
+type StockStream struct {
+	*marketStream
+}
+
+func NewStockStream(feed string) *StockStream {
+	s := newMarketStream(DataStreamURL+"/v2/"+feed, []string{
+		ChannelTrades, ChannelQuotes, ChannelBars, ChannelUpdatedBars, ChannelDailyBars, ChannelStatuses, ChannelLULDs,
+	})
+	s.encode = json.Marshal
+	s.decode = decodeJSONMessages
+	s.parse = parseStockMessage
+	return &StockStream{s}
+}
+
+// Subscribe adds symbols, sent right away when connected and restored after reconnects
+func (s *StockStream) Subscribe(sub StockSubscription) error {
+	return s.update("subscribe", sub.channels())
+}
+
+func (s *StockStream) Unsubscribe(sub StockSubscription) error {
+	return s.update("unsubscribe", sub.channels())
+}
+
+func decodeJSONMessages(data []byte) ([]map[string]interface{}, error) {
+	var msgs []map[string]interface{}
+	err := json.Unmarshal(data, &msgs)
+	return msgs, err
+}
+
+func parseStreamBar(m map[string]interface{}, channel string) *StreamBar {
+	return &StreamBar{
+		Symbol:  getString(m["S"]),
+		Channel: channel,
+		Bar: Bar{
+			Close:          getFloat64(m["c"]),
+			High:           getFloat64(m["h"]),
+			Low:            getFloat64(m["l"]),
+			NumberOfTrades: getInt(m["n"]),
+			Open:           getFloat64(m["o"]),
+			Timestamp:      getTime(m["t"]),
+			Volume:         getInt(m["v"]),
+			VWAP:           getFloat64(m["vw"]),
+		},
+	}
+}
+
+func parseStockMessage(m map[string]interface{}) StreamEvent {
+	switch getString(m["T"]) {
+	case "t":
+		conditions := getStrings(m["c"])
+		return &StreamTrade{
+			Symbol:     getString(m["S"]),
+			ID:         int64(getFloat64(m["i"])),
+			Tape:       getString(m["z"]),
+			Conditions: conditions,
+			Trade: Trade{
+				Condition: strings.Join(conditions, ","),
+				Price:     getFloat64(m["p"]),
+				Size:      getInt(m["s"]),
+				Timestamp: getTime(m["t"]),
+				Exchange:  getString(m["x"]),
+			},
+		}
+	case "q":
+		conditions := getStrings(m["c"])
+		return &StreamQuote{
+			Symbol:     getString(m["S"]),
+			Tape:       getString(m["z"]),
+			Conditions: conditions,
+			Quote: Quote{
+				AskPrice:    getFloat64(m["ap"]),
+				AskSize:     getInt(m["as"]),
+				AskExchange: getString(m["ax"]),
+				BidPrice:    getFloat64(m["bp"]),
+				BidSize:     getInt(m["bs"]),
+				BidExchange: getString(m["bx"]),
+				Condition:   strings.Join(conditions, ","),
+				Timestamp:   getTime(m["t"]),
+			},
+		}
+	case "b":
+		return parseStreamBar(m, ChannelBars)
+	case "u":
+		return parseStreamBar(m, ChannelUpdatedBars)
+	case "d":
+		return parseStreamBar(m, ChannelDailyBars)
+	case "s":
+		return &TradingStatus{
+			Symbol:        getString(m["S"]),
+			StatusCode:    getString(m["sc"]),
+			StatusMessage: getString(m["sm"]),
+			ReasonCode:    getString(m["rc"]),
+			ReasonMessage: getString(m["rm"]),
+			Tape:          getString(m["z"]),
+			Timestamp:     getTime(m["t"]),
+		}
+	case "l":
+		return &LULD{
+			Symbol:    getString(m["S"]),
+			LimitUp:   getFloat64(m["u"]),
+			LimitDown: getFloat64(m["d"]),
+			Indicator: getString(m["i"]),
+			Tape:      getString(m["z"]),
+			Timestamp: getTime(m["t"]),
+		}
+	case "c":
+		return &TradeCorrection{
+			Symbol:              getString(m["S"]),
+			Exchange:            getString(m["x"]),
+			Tape:                getString(m["z"]),
+			OriginalID:          int64(getFloat64(m["oi"])),
+			OriginalPrice:       getFloat64(m["op"]),
+			OriginalSize:        getInt(m["os"]),
+			OriginalConditions:  getStrings(m["oc"]),
+			CorrectedID:         int64(getFloat64(m["ci"])),
+			CorrectedPrice:      getFloat64(m["cp"]),
+			CorrectedSize:       getInt(m["cs"]),
+			CorrectedConditions: getStrings(m["cc"]),
+			Timestamp:           getTime(m["t"]),
+		}
+	case "x":
+		return &TradeCancel{
+			Symbol:    getString(m["S"]),
+			ID:        int64(getFloat64(m["i"])),
+			Exchange:  getString(m["x"]),
+			Price:     getFloat64(m["p"]),
+			Size:      getInt(m["s"]),
+			Action:    getString(m["a"]),
+			Tape:      getString(m["z"]),
+			Timestamp: getTime(m["t"]),
+		}
+	}
+	return nil
+}
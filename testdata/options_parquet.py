@@ -0,0 +1,356 @@
+"""Writes options.parquet and options_defaults.parquet, fixtures for ReadOptionsParquet.
+
+options.parquet has the layout pyarrow produces for
+
+    pq.write_table(pa.Table.from_pandas(df), "options.parquet",
+                   compression="none", use_dictionary=False, row_group_size=2)
+
+OPTIONAL columns with RLE/bit-packed definition levels, STRING and TIMESTAMP(MICROS, UTC)
+logical types with their converted types, INT32 and FLOAT columns, the pandas index column,
+column statistics and two row groups.
+
+options_defaults.parquet has the layout of write_table with its defaults
+
+    pq.write_table(pa.Table.from_pandas(df, preserve_index=False), "options_defaults.parquet")
+
+the same columns in one row group, snappy compressed, with a PLAIN dictionary page and
+RLE_DICTIONARY data page for every column but the boolean one.
+
+Both files are written by this script with the standard library only, so they can be
+regenerated without pyarrow:
+
+    python3 testdata/options_parquet.py
+
+To check the reader against pyarrow itself, write the same table with the pq.write_table calls
+above and read it with ReadOptionsParquet.
+"""
+
+import json
+import os
+import struct
+from datetime import datetime, timezone
+
+# Thrift compact protocol types
+BOOL_TRUE, BOOL_FALSE, I16, I32, I64, BINARY, LIST, STRUCT = 1, 2, 4, 5, 6, 8, 9, 12
+
+# Parquet physical types, converted types and repetition
+BOOLEAN, INT32, INT64, FLOAT, DOUBLE, BYTE_ARRAY = 0, 1, 2, 4, 5, 6
+UTF8, TIMESTAMP_MICROS = 0, 10
+OPTIONAL = 1
+PLAIN, RLE, RLE_DICTIONARY = 0, 3, 8
+UNCOMPRESSED, SNAPPY = 0, 1
+DATA_PAGE, DICTIONARY_PAGE = 0, 2
+
+
+def varint(v):
+    out = bytearray()
+    while True:
+        if v < 0x80:
+            out.append(v)
+            return bytes(out)
+        out.append(v & 0x7F | 0x80)
+        v >>= 7
+
+
+def zigzag(v):
+    return varint((v << 1) ^ (v >> 63))
+
+
+class Struct:
+    """A Thrift compact struct, fields are added in increasing id order"""
+
+    def __init__(self):
+        self.out = bytearray()
+        self.last = 0
+
+    def header(self, fid, typ):
+        delta = fid - self.last
+        if 0 < delta <= 15:
+            self.out.append(delta << 4 | typ)
+        else:
+            self.out.append(typ)
+            self.out += zigzag(fid)
+        self.last = fid
+
+    def i16(self, fid, v):
+        self.header(fid, I16)
+        self.out += zigzag(v)
+        return self
+
+    def i32(self, fid, v):
+        self.header(fid, I32)
+        self.out += zigzag(v)
+        return self
+
+    def i64(self, fid, v):
+        self.header(fid, I64)
+        self.out += zigzag(v)
+        return self
+
+    def bool(self, fid, v):
+        self.header(fid, BOOL_TRUE if v else BOOL_FALSE)
+        return self
+
+    def binary(self, fid, v):
+        if isinstance(v, str):
+            v = v.encode()
+        self.header(fid, BINARY)
+        self.out += varint(len(v)) + v
+        return self
+
+    def struct(self, fid, s):
+        self.header(fid, STRUCT)
+        self.out += s.bytes()
+        return self
+
+    def list(self, fid, typ, items):
+        self.header(fid, LIST)
+        if len(items) < 15:
+            self.out.append(len(items) << 4 | typ)
+        else:
+            self.out.append(0xF0 | typ)
+            self.out += varint(len(items))
+        for item in items:
+            if typ == STRUCT:
+                self.out += item.bytes()
+            elif typ == BINARY:
+                b = item.encode()
+                self.out += varint(len(b)) + b
+            else:
+                self.out += zigzag(item)
+        return self
+
+    def bytes(self):
+        return bytes(self.out) + b"\x00"
+
+
+def micros(s):
+    t = datetime.fromisoformat(s).replace(tzinfo=timezone.utc)
+    return int(t.timestamp()) * 1000000 + t.microsecond
+
+
+deliverables = json.dumps(
+    [
+        {
+            "type": "equity",
+            "symbol": "AAPL",
+            "asset_id": "b0b6dd9d-8b9b-48a9-ba46-b9d54906e415",
+            "amount": 100,
+            "allocation_percentage": 100,
+            "settlement_type": "T+1",
+            "settlement_method": "CCC",
+            "delayed_settlement": False,
+        }
+    ],
+    separators=(",", ":"),
+)
+
+# name, physical type, converted type, logical type, values (None is null)
+columns = [
+    ("symbol", BYTE_ARRAY, UTF8, "string",
+     ["AAPL250117C00200000", "AAPL250117P00200000", "AAPL250221C00210000"]),
+    ("underlying_symbol", BYTE_ARRAY, UTF8, "string", ["AAPL", "AAPL", "AAPL"]),
+    ("type", BYTE_ARRAY, UTF8, "string", ["call", "put", "call"]),
+    ("expiration_date", BYTE_ARRAY, UTF8, "string", ["2025-01-17", "2025-01-17", "2025-02-21"]),
+    ("strike_price", DOUBLE, None, None, [200.0, 200.0, 210.0]),
+    ("multiplier", INT32, None, None, [100, 100, 100]),
+    ("tradable", BOOLEAN, None, None, [True, False, True]),
+    ("open_interest", INT64, None, None, [1520, 310, None]),
+    ("deliverables", BYTE_ARRAY, UTF8, "string", [deliverables, None, None]),
+    ("implied_volatility", FLOAT, None, None, [0.25, 0.375, None]),
+    ("delta", DOUBLE, None, None, [0.5625, -0.4375, None]),
+    ("quote_bid_price", DOUBLE, None, None, [4.1, 3.85, None]),
+    ("quote_ask_price", DOUBLE, None, None, [4.25, 3.95, None]),
+    ("quote_timestamp", INT64, TIMESTAMP_MICROS, "timestamp",
+     [micros("2025-01-10T15:59:59.123456"), micros("2025-01-10T15:59:58.000001"), None]),
+    ("__index_level_0__", INT64, None, None, [0, 1, 2]),
+]
+
+
+def plain(typ, values):
+    out = bytearray()
+    if typ == BOOLEAN:
+        bits = 0
+        for i, v in enumerate(values):
+            bits |= int(v) << i
+        return bits.to_bytes((len(values) + 7) // 8, "little")
+    for v in values:
+        if typ == INT32:
+            out += struct.pack("<i", v)
+        elif typ == INT64:
+            out += struct.pack("<q", v)
+        elif typ == FLOAT:
+            out += struct.pack("<f", v)
+        elif typ == DOUBLE:
+            out += struct.pack("<d", v)
+        else:
+            b = v.encode()
+            out += struct.pack("<I", len(b)) + b
+    return bytes(out)
+
+
+def levels(values):
+    """Definition levels as one bit-packed run, like parquet-cpp writes short mixed runs"""
+    bits = 0
+    for i, v in enumerate(values):
+        bits |= (v is not None) << i
+    groups = (len(values) + 7) // 8
+    run = varint(groups << 1 | 1) + bits.to_bytes(groups, "little")
+    return struct.pack("<I", len(run)) + run
+
+
+def stat_bytes(typ, v):
+    return plain(typ, [v]) if typ != BYTE_ARRAY else v.encode()
+
+
+def statistics(typ, values):
+    s = Struct()
+    present = [v for v in values if v is not None]
+    if present and typ != BOOLEAN:
+        s.binary(5, stat_bytes(typ, max(present)))
+        s.binary(6, stat_bytes(typ, min(present)))
+    s.i64(3, len(values) - len(present))
+    return s
+
+
+def snappy(data):
+    """Snappy block compression, greedy matches of 4 bytes and more within 64 KiB"""
+    out = bytearray(varint(len(data)))
+    literal = 0  # start of the pending literal
+
+    def emit_literal(end):
+        n = end - literal
+        if n == 0:
+            return
+        if n <= 60:
+            out.append((n - 1) << 2)
+        else:
+            size = (n - 1).to_bytes(4, "little").rstrip(b"\x00")
+            out.append((59 + len(size)) << 2)
+            out.extend(size)
+        out.extend(data[literal:end])
+
+    table = {}
+    i = 0
+    while i + 4 <= len(data):
+        key = bytes(data[i:i + 4])
+        candidate = table.get(key)
+        table[key] = i
+        if candidate is None or i - candidate > 0xFFFF:
+            i += 1
+            continue
+        length = 4
+        while i + length < len(data) and data[candidate + length] == data[i + length] and length < 64:
+            length += 1
+        emit_literal(i)
+        offset = i - candidate
+        if length <= 11 and offset < 2048:
+            out.append((offset >> 8) << 5 | (length - 4) << 2 | 1)
+            out.append(offset & 0xFF)
+        else:
+            out.append((length - 1) << 2 | 2)
+            out += struct.pack("<H", offset)
+        i += length
+        literal = i
+    i = len(data)
+    emit_literal(i)
+    return bytes(out)
+
+
+def hybrid(values, width):
+    """RLE/bit-packed hybrid values: one RLE run if they are all equal, else bit-packed groups"""
+    if len(set(values)) == 1:
+        return varint(len(values) << 1) + values[0].to_bytes((width + 7) // 8, "little")
+    groups = (len(values) + 7) // 8
+    bits = 0
+    for i, v in enumerate(values):
+        bits |= v << (i * width)
+    return varint(groups << 1 | 1) + bits.to_bytes(groups * width, "little")
+
+
+def write(name, row_groups, index, dictionary, codec):
+    cols = columns if index else [c for c in columns if c[0] != "__index_level_0__"]
+    compress = snappy if codec == SNAPPY else bytes
+    body = bytearray(b"PAR1")
+    groups = []
+    for start, end in row_groups:
+        chunks = []
+        group_offset = len(body)
+        group_size = group_uncompressed = 0
+        for col, typ, converted, logical, values in cols:
+            values = values[start:end]
+            present = [v for v in values if v is not None]
+            offset = len(body)
+            uncompressed = 0
+            dictionary_offset = None
+            encodings = [PLAIN, RLE]
+            if dictionary and typ != BOOLEAN:
+                entries = list(dict.fromkeys(present))
+                page = plain(typ, entries)
+                header = Struct().i32(1, DICTIONARY_PAGE).i32(2, len(page)).i32(3, len(compress(page)))
+                header.struct(7, Struct().i32(1, len(entries)).i32(2, PLAIN))
+                dictionary_offset = offset
+                body += header.bytes() + compress(page)
+                uncompressed += len(header.bytes()) + len(page)
+                width = (len(entries) - 1).bit_length()
+                encoded = bytes([width]) + hybrid([entries.index(v) for v in present], width) if present else b""
+                encoding = RLE_DICTIONARY
+                encodings = [PLAIN, RLE, RLE_DICTIONARY]
+            else:
+                encoded = plain(typ, present)
+                encoding = PLAIN
+
+            data_offset = len(body)
+            page = levels(values) + encoded
+            header = Struct().i32(1, DATA_PAGE).i32(2, len(page)).i32(3, len(compress(page)))
+            header.struct(5, Struct().i32(1, len(values)).i32(2, encoding).i32(3, RLE).i32(4, RLE)
+                          .struct(5, statistics(typ, values)))
+            body += header.bytes() + compress(page)
+            uncompressed += len(header.bytes()) + len(page)
+            size = len(body) - offset
+            group_size += size
+            group_uncompressed += uncompressed
+
+            meta = Struct().i32(1, typ).list(2, I32, encodings).list(3, BINARY, [col]).i32(4, codec)
+            meta.i64(5, len(values)).i64(6, uncompressed).i64(7, size).i64(9, data_offset)
+            if dictionary_offset is not None:
+                meta.i64(11, dictionary_offset)
+            meta.struct(12, statistics(typ, values))
+            chunks.append(Struct().i64(2, offset).struct(3, meta))
+        groups.append(Struct().list(1, STRUCT, chunks).i64(2, group_uncompressed).i64(3, end - start)
+                      .i64(5, group_offset).i64(6, group_size).i16(7, len(groups)))
+
+    schema = [Struct().binary(4, "schema").i32(5, len(cols))]
+    for col, typ, converted, logical, values in cols:
+        element = Struct().i32(1, typ).i32(3, OPTIONAL).binary(4, col)
+        if converted is not None:
+            element.i32(6, converted)
+        if logical == "string":
+            element.struct(10, Struct().struct(1, Struct()))
+        elif logical == "timestamp":
+            unit = Struct().struct(2, Struct())
+            element.struct(10, Struct().struct(8, Struct().bool(1, True).struct(2, unit)))
+        schema.append(element)
+
+    pandas_meta = json.dumps({
+        "index_columns": ["__index_level_0__"] if index else
+        [{"kind": "range", "name": None, "start": 0, "stop": len(cols[0][4]), "step": 1}],
+        "columns": [{"name": c[0], "field_name": c[0]} for c in cols],
+        "creator": {"library": "pyarrow", "version": "17.0.0"},
+        "pandas_version": "2.2.2",
+    })
+    footer = Struct().i32(1, 2).list(2, STRUCT, schema).i64(3, len(cols[0][4]))
+    footer.list(4, STRUCT, groups)
+    footer.list(5, STRUCT, [Struct().binary(1, "pandas").binary(2, pandas_meta)])
+    footer.binary(6, "parquet-cpp-arrow version 17.0.0")
+    footer.list(7, STRUCT, [Struct().struct(1, Struct()) for _ in cols])
+    footer = footer.bytes()
+
+    body += footer + struct.pack("<I", len(footer)) + b"PAR1"
+    path = os.path.join(os.path.dirname(os.path.abspath(__file__)), name)
+    with open(path, "wb") as f:
+        f.write(body)
+
+
+write("options.parquet", [(0, 2), (2, 3)], index=True, dictionary=False, codec=UNCOMPRESSED)
+write("options_defaults.parquet", [(0, 3)], index=False, dictionary=True, codec=SNAPPY)
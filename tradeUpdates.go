@@ -0,0 +1,335 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Events of the trade_updates stream
+const (
+	EventNew                  = "new"
+	EventFill                 = "fill"
+	EventPartialFill          = "partial_fill"
+	EventCanceled             = "canceled"
+	EventExpired              = "expired"
+	EventDoneForDay           = "done_for_day"
+	EventReplaced             = "replaced"
+	EventRejected             = "rejected"
+	EventPendingNew           = "pending_new"
+	EventPendingCancel        = "pending_cancel"
+	EventPendingReplace       = "pending_replace"
+	EventStopped              = "stopped"
+	EventSuspended            = "suspended"
+	EventCalculated           = "calculated"
+	EventOrderReplaceRejected = "order_replace_rejected"
+	EventOrderCancelRejected  = "order_cancel_rejected"
+)
+
+// TradeUpdate is one event of the trade_updates stream. Price, Qty and PositionQty are set
+// on fills. Resynced updates were rebuilt from the orders API after a reconnect, their Qty is
+// the quantity filled while disconnected and Price the average fill price of the order.
+type TradeUpdate struct {
+	Event       string    `json:"event"`
+	ExecutionID string    `json:"execution_id"`
+	Order       Order     `json:"order"`
+	Timestamp   time.Time `json:"timestamp"`
+	Price       Decimal   `json:"price"`
+	Qty         Decimal   `json:"qty"`
+	PositionQty Decimal   `json:"position_qty"`
+	Resynced    bool      `json:"-"`
+}
+
+// final reports whether no further updates follow for the order
+func (u TradeUpdate) final() bool {
+	switch u.Event {
+	case EventFill, EventCanceled, EventExpired, EventReplaced, EventRejected:
+		return true
+	}
+	return false
+}
+
+// TradeUpdatesStream delivers order events in order, reconnecting with backoff when the
+// connection drops
+type TradeUpdatesStream struct {
+	// Resync replays order changes missed while disconnected, on by default
+	Resync bool
+
+	// Broker sets the endpoint, keys and client of the stream and its resync, the zero value
+	// uses the package settings
+	Broker APIBroker
+
+	connected  bool
+	lastEvent  time.Time
+	openOrders map[string]bool
+	resynced   map[string]time.Time
+	filledQty  map[string]Decimal
+}
+
+func NewTradeUpdatesStream() *TradeUpdatesStream {
+	return &TradeUpdatesStream{
+		Resync:     true,
+		openOrders: make(map[string]bool),
+		resynced:   make(map[string]time.Time),
+		filledQty:  make(map[string]Decimal),
+	}
+}
+
+type streamMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func (s *TradeUpdatesStream) connect() (*wsConn, error) {
+	keyID, secretKey := s.Broker.keys()
+	if keyID == "" || secretKey == "" {
+		return nil, fmt.Errorf("APIKeyID or APISecretKey is not set")
+	}
+
+	conn, err := dialWebSocket(streamURL(s.Broker.tradingURL(), "/stream"), 10*time.Second, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request := func(payload interface{}, stream string) (json.RawMessage, error) {
+		data, _ := json.Marshal(payload)
+		if err := conn.WriteMessage(data); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		defer conn.SetReadDeadline(time.Time{})
+		_, reply, err := conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		var msg streamMessage
+		if err := json.Unmarshal(reply, &msg); err != nil {
+			return nil, fmt.Errorf("error parsing stream reply: %v", err)
+		}
+		if msg.Stream != stream {
+			return nil, fmt.Errorf("unexpected stream reply %s", string(reply))
+		}
+		return msg.Data, nil
+	}
+
+	data, err := request(map[string]string{"action": "auth", "key": keyID, "secret": secretKey}, "authorization")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var auth struct {
+		Status string `json:"status"`
+	}
+	json.Unmarshal(data, &auth)
+	if auth.Status != "authorized" {
+		conn.Close()
+		return nil, errUnauthorized
+	}
+
+	listen := map[string]interface{}{"action": "listen", "data": map[string][]string{"streams": {"trade_updates"}}}
+	if _, err := request(listen, "listening"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+var errUnauthorized = fmt.Errorf("stream authorization failed, check APIKeyID and APISecretKey")
+
+// Run connects and calls handler for every update, one at a time and in order, until ctx is
+// done or authorization fails
+func (s *TradeUpdatesStream) Run(ctx context.Context, handler func(TradeUpdate)) error {
+	attempt := 0
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		conn, err := s.connect()
+		if err == errUnauthorized {
+			return err
+		}
+		if err != nil {
+			wait := backoff(attempt)
+			fmt.Printf("Trade updates stream connection failed: %v, retrying in %v\n", err, wait)
+			attempt++
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		attempt = 0
+
+		if !s.connected && s.lastEvent.IsZero() {
+			// Server time, so a local clock ahead of it does not hide changes from the resync
+			s.lastEvent = conn.serverTime
+			if s.lastEvent.IsZero() {
+				s.lastEvent = time.Now()
+			}
+		}
+		if s.connected && s.Resync {
+			if err := s.resync(handler); err != nil {
+				fmt.Printf("Trade updates resync failed: %v\n", err)
+			}
+		}
+		s.connected = true
+
+		err = s.listen(ctx, conn, handler)
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("Trade updates stream disconnected: %v, reconnecting\n", err)
+	}
+}
+
+// Updates runs the stream in the background and delivers the updates on a channel that is
+// closed when ctx is done
+func (s *TradeUpdatesStream) Updates(ctx context.Context) <-chan TradeUpdate {
+	updates := make(chan TradeUpdate, 256)
+	go func() {
+		defer close(updates)
+		err := s.Run(ctx, func(u TradeUpdate) {
+			select {
+			case updates <- u:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil && err != ctx.Err() {
+			fmt.Printf("Trade updates stream stopped: %v\n", err)
+		}
+	}()
+	return updates
+}
+
+func (s *TradeUpdatesStream) listen(ctx context.Context, conn *wsConn, handler func(TradeUpdate)) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var msg streamMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Stream != "trade_updates" {
+			continue
+		}
+		var update TradeUpdate
+		if err := json.Unmarshal(msg.Data, &update); err != nil {
+			fmt.Printf("Error parsing trade update: %v\n", err)
+			continue
+		}
+
+		// Skip events already replayed by the last resync
+		if seen, ok := s.resynced[update.Order.ID]; ok {
+			if !update.Order.UpdatedAt.After(seen) {
+				continue
+			}
+			delete(s.resynced, update.Order.ID)
+		}
+
+		s.deliver(update, handler)
+	}
+}
+
+func (s *TradeUpdatesStream) deliver(update TradeUpdate, handler func(TradeUpdate)) {
+	if update.final() {
+		delete(s.openOrders, update.Order.ID)
+		delete(s.filledQty, update.Order.ID)
+	} else {
+		s.openOrders[update.Order.ID] = true
+		s.filledQty[update.Order.ID] = update.Order.FilledQty
+	}
+	if update.Timestamp.After(s.lastEvent) {
+		s.lastEvent = update.Timestamp
+	}
+	handler(update)
+}
+
+// statusEvents maps order statuses to the event that leads to them
+var statusEvents = map[string]string{
+	"new":              EventNew,
+	"accepted":         EventNew,
+	"pending_new":      EventPendingNew,
+	"partially_filled": EventPartialFill,
+	"filled":           EventFill,
+	"done_for_day":     EventDoneForDay,
+	"canceled":         EventCanceled,
+	"expired":          EventExpired,
+	"replaced":         EventReplaced,
+	"pending_cancel":   EventPendingCancel,
+	"pending_replace":  EventPendingReplace,
+	"rejected":         EventRejected,
+	"stopped":          EventStopped,
+	"suspended":        EventSuspended,
+	"calculated":       EventCalculated,
+}
+
+// resync emits one update per order changed since the last delivered event, covering the most
+// recent orders as well as every order known to be open before the disconnect
+func (s *TradeUpdatesStream) resync(handler func(TradeUpdate)) error {
+	orders, err := s.Broker.ListOrders(ListOrdersReq{Status: "all", Limit: maxOrdersPage, Nested: true})
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]Order)
+	for _, order := range orders {
+		for _, o := range order.Flatten() {
+			byID[o.ID] = o
+		}
+	}
+	for id := range s.openOrders {
+		if _, ok := byID[id]; ok {
+			continue
+		}
+		order, err := s.Broker.GetOrder(id)
+		if err != nil {
+			return err
+		}
+		byID[id] = order
+	}
+
+	var changed []Order
+	for _, order := range byID {
+		if order.UpdatedAt.After(s.lastEvent) {
+			changed = append(changed, order)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].UpdatedAt.Before(changed[j].UpdatedAt) })
+
+	for _, order := range changed {
+		event, ok := statusEvents[order.Status]
+		if !ok {
+			event = order.Status
+		}
+		update := TradeUpdate{
+			Event:     event,
+			Order:     order,
+			Timestamp: order.UpdatedAt,
+			Resynced:  true,
+		}
+		if event == EventFill || event == EventPartialFill {
+			// The quantity filled while disconnected, at the average price of the whole order
+			update.Price = order.FilledAvgPrice
+			update.Qty = order.FilledQty - s.filledQty[order.ID]
+		}
+		s.resynced[order.ID] = order.UpdatedAt
+		s.deliver(update, handler)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package alpacaApiClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tradeUpdatesServer serves the trade_updates stream and the orders API. Each connection is
+// authorized and handed to the next function of sessions, the last one is used for the rest.
+type tradeUpdatesServer struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	orders   map[string]Order
+	unlisted map[string]bool // left out of GET /v2/orders, like orders beyond its first page
+	sessions []func(*wsPeer)
+	session  int
+	listed   chan bool // receives on every GET /v2/orders
+}
+
+func newTradeUpdatesServer(t *testing.T, date time.Time, sessions ...func(*wsPeer)) *tradeUpdatesServer {
+	s := &tradeUpdatesServer{orders: make(map[string]Order), unlisted: make(map[string]bool), sessions: sessions, listed: make(chan bool, 10)}
+	stream := wsHandler(t, date, s.stream(t))
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/stream":
+			stream(w, r)
+		case r.URL.Path == "/v2/orders":
+			s.mutex.Lock()
+			orders := []Order{}
+			for id, order := range s.orders {
+				if !s.unlisted[id] {
+					orders = append(orders, order)
+				}
+			}
+			s.mutex.Unlock()
+			json.NewEncoder(w).Encode(orders)
+			s.listed <- true
+		case strings.HasPrefix(r.URL.Path, "/v2/orders/"):
+			s.mutex.Lock()
+			order, ok := s.orders[strings.TrimPrefix(r.URL.Path, "/v2/orders/")]
+			s.mutex.Unlock()
+			if !ok {
+				http.Error(w, `{"message": "order not found"}`, http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(order)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func (s *tradeUpdatesServer) stream(t *testing.T) func(*wsPeer) {
+	return func(p *wsPeer) {
+		if _, payload := p.read(); !strings.Contains(string(payload), `"auth"`) {
+			t.Errorf("first message %s, want the authorization", payload)
+		}
+		p.writeText(`{"stream":"authorization","data":{"status":"authorized","action":"authenticate"}}`)
+		if _, payload := p.read(); !strings.Contains(string(payload), `"trade_updates"`) {
+			t.Errorf("second message %s, want the listen request", payload)
+		}
+		p.writeText(`{"stream":"listening","data":{"streams":["trade_updates"]}}`)
+
+		s.mutex.Lock()
+		session := s.sessions[s.session]
+		if s.session < len(s.sessions)-1 {
+			s.session++
+		}
+		s.mutex.Unlock()
+		session(p)
+	}
+}
+
+func (s *tradeUpdatesServer) setOrder(order Order, listed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.orders[order.ID] = order
+	s.unlisted[order.ID] = !listed
+}
+
+func (s *tradeUpdatesServer) broker() APIBroker {
+	return APIBroker{TradingURL: s.URL, APIKeyID: "key", APISecretKey: "secret", Client: s.Client()}
+}
+
+func tradeUpdateMessage(event string, order Order, qty, price float64) string {
+	update := TradeUpdate{Event: event, Order: order, Timestamp: order.UpdatedAt, Qty: Decimal(qty), Price: Decimal(price)}
+	data, _ := json.Marshal(update)
+	return fmt.Sprintf(`{"stream":"trade_updates","data":%s}`, data)
+}
+
+func TestTradeUpdatesResync(t *testing.T) {
+	// The server clock is behind the local one, the resync must be bounded by the server's Date
+	date := time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return date.Add(time.Duration(seconds) * time.Second) }
+
+	old := Order{ID: "old", Symbol: "AAPL", Status: "filled", Qty: 1, FilledQty: 1, UpdatedAt: at(-3600)}
+	missed := Order{ID: "missed", Symbol: "AAPL", Status: "new", Qty: 5, UpdatedAt: at(3)}
+	partial := Order{ID: "partial", Symbol: "AAPL", Status: "partially_filled", Qty: 10, FilledQty: 2, FilledAvgPrice: 1.4, UpdatedAt: at(4)}
+	filled := partial
+	filled.Status, filled.FilledQty, filled.FilledAvgPrice, filled.UpdatedAt = "filled", 10, 1.5, at(5)
+	open := Order{ID: "open", Symbol: "MSFT", Status: "new", Qty: 3, UpdatedAt: at(4)}
+	canceled := open
+	canceled.Status, canceled.UpdatedAt = "canceled", at(6)
+
+	var srv *tradeUpdatesServer
+	srv = newTradeUpdatesServer(t, date,
+		// Disconnect before any event, meanwhile an order is submitted
+		func(p *wsPeer) {
+			srv.setOrder(old, true)
+			srv.setOrder(missed, true)
+			p.write(true, wsClose, []byte{0x03, 0xE9})
+			p.read()
+		},
+		// Two live events after the resync, then the connection drops while the partial fill
+		// completes and the open order is canceled
+		func(p *wsPeer) {
+			<-srv.listed
+			srv.setOrder(partial, true)
+			srv.setOrder(open, true)
+			p.writeText(tradeUpdateMessage(EventPartialFill, partial, 2, 1.4))
+			p.writeText(tradeUpdateMessage(EventNew, open, 0, 0))
+			time.Sleep(100 * time.Millisecond)
+			srv.setOrder(filled, true)
+			srv.setOrder(canceled, false)
+		},
+		// Stay connected until the client leaves
+		func(p *wsPeer) {
+			p.conn.SetReadDeadline(time.Time{})
+			p.reader.ReadByte()
+		},
+	)
+	defer srv.Close()
+
+	stream := NewTradeUpdatesStream()
+	stream.Broker = srv.broker()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type event struct {
+		Event    string
+		ID       string
+		Qty      Decimal
+		Price    Decimal
+		Resynced bool
+	}
+	want := []event{
+		{EventNew, "missed", 0, 0, true},
+		{EventPartialFill, "partial", 2, 1.4, false},
+		{EventNew, "open", 0, 0, false},
+		{EventFill, "partial", 8, 1.5, true},
+		{EventCanceled, "open", 0, 0, true},
+	}
+	var got []event
+	err := stream.Run(ctx, func(u TradeUpdate) {
+		got = append(got, event{u.Event, u.Order.ID, u.Qty, u.Price, u.Resynced})
+		if len(got) == len(want) {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got updates\n%+v\nwant\n%+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("update %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
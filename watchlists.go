@@ -0,0 +1,134 @@
+package alpacaApiClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type Watchlist struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Assets    []Asset   `json:"assets"`
+}
+
+func (w Watchlist) Symbols() []string {
+	var symbols []string
+	for _, asset := range w.Assets {
+		symbols = append(symbols, asset.Symbol)
+	}
+	return symbols
+}
+
+func (b APIBroker) watchlistRequest(method string, endpoint string, payload interface{}) (Watchlist, error) {
+	var watchlist Watchlist
+
+	_, bodyStr, err := b.APIRequestMethod(method, endpoint, payload)
+	if err != nil {
+		return watchlist, err
+	}
+	if err := json.Unmarshal([]byte(bodyStr), &watchlist); err != nil {
+		return watchlist, fmt.Errorf("error parsing watchlist: %v", err)
+	}
+	return watchlist, nil
+}
+
+func (b APIBroker) watchlistURL(watchlistID string) string {
+	return b.tradingURL() + "/v2/watchlists/" + url.PathEscape(watchlistID)
+}
+
+// ListWatchlists returns all watchlists of the account, without their assets
+func ListWatchlists() ([]Watchlist, error) {
+	return defaultBroker.ListWatchlists()
+}
+
+func (b APIBroker) ListWatchlists() ([]Watchlist, error) {
+	_, bodyStr, err := b.APIRequestMethod("GET", b.tradingURL()+"/v2/watchlists", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var watchlists []Watchlist
+	if err := json.Unmarshal([]byte(bodyStr), &watchlists); err != nil {
+		return nil, fmt.Errorf("error parsing watchlists: %v", err)
+	}
+	return watchlists, nil
+}
+
+func CreateWatchlist(name string, symbols []string) (Watchlist, error) {
+	return defaultBroker.CreateWatchlist(name, symbols)
+}
+
+func (b APIBroker) CreateWatchlist(name string, symbols []string) (Watchlist, error) {
+	payload := map[string]interface{}{"name": name, "symbols": symbols}
+	return b.watchlistRequest("POST", b.tradingURL()+"/v2/watchlists", payload)
+}
+
+func GetWatchlist(watchlistID string) (Watchlist, error) {
+	return defaultBroker.GetWatchlist(watchlistID)
+}
+
+func (b APIBroker) GetWatchlist(watchlistID string) (Watchlist, error) {
+	return b.watchlistRequest("GET", b.watchlistURL(watchlistID), nil)
+}
+
+func GetWatchlistByName(name string) (Watchlist, error) {
+	return defaultBroker.GetWatchlistByName(name)
+}
+
+func (b APIBroker) GetWatchlistByName(name string) (Watchlist, error) {
+	return b.watchlistRequest("GET", b.tradingURL()+"/v2/watchlists:by_name?name="+url.QueryEscape(name), nil)
+}
+
+// UpdateWatchlist renames the watchlist and replaces its symbols
+func UpdateWatchlist(watchlistID string, name string, symbols []string) (Watchlist, error) {
+	return defaultBroker.UpdateWatchlist(watchlistID, name, symbols)
+}
+
+func (b APIBroker) UpdateWatchlist(watchlistID string, name string, symbols []string) (Watchlist, error) {
+	payload := map[string]interface{}{"name": name, "symbols": symbols}
+	return b.watchlistRequest("PUT", b.watchlistURL(watchlistID), payload)
+}
+
+func AddWatchlistSymbol(watchlistID string, symbol string) (Watchlist, error) {
+	return defaultBroker.AddWatchlistSymbol(watchlistID, symbol)
+}
+
+func (b APIBroker) AddWatchlistSymbol(watchlistID string, symbol string) (Watchlist, error) {
+	return b.watchlistRequest("POST", b.watchlistURL(watchlistID), map[string]string{"symbol": symbol})
+}
+
+func RemoveWatchlistSymbol(watchlistID string, symbol string) (Watchlist, error) {
+	return defaultBroker.RemoveWatchlistSymbol(watchlistID, symbol)
+}
+
+func (b APIBroker) RemoveWatchlistSymbol(watchlistID string, symbol string) (Watchlist, error) {
+	return b.watchlistRequest("DELETE", b.watchlistURL(watchlistID)+"/"+url.PathEscape(symbol), nil)
+}
+
+func DeleteWatchlist(watchlistID string) error {
+	return defaultBroker.DeleteWatchlist(watchlistID)
+}
+
+func (b APIBroker) DeleteWatchlist(watchlistID string) error {
+	_, _, err := b.APIRequestMethod("DELETE", b.watchlistURL(watchlistID), nil)
+	return err
+}
+
+// OptionRequestsFromWatchlist expands the watchlist called name into one option request per
+// symbol, ready for MergeRequests
+func OptionRequestsFromWatchlist(name string, template OptionURLReq) ([]OptionURLReq, error) {
+	return defaultBroker.OptionRequestsFromWatchlist(name, template)
+}
+
+func (b APIBroker) OptionRequestsFromWatchlist(name string, template OptionURLReq) ([]OptionURLReq, error) {
+	watchlist, err := b.GetWatchlistByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting watchlist %s: %v", name, err)
+	}
+	return OptionRequests(watchlist.Symbols(), template), nil
+}
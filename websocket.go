@@ -0,0 +1,282 @@
+package alpacaApiClient
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes of RFC 6455
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// Largest message accepted from the streams, guards against corrupt length headers
+const wsMaxMessage = 64 << 20
+
+// wsConn is a minimal WebSocket client connection, enough for the Alpaca streams
+type wsConn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+
+	// Server time of the handshake from its Date header, zero when it has none
+	serverTime time.Time
+}
+
+// dialWebSocket opens a WebSocket connection to a ws:// or wss:// URL, sending header with the handshake
+func dialWebSocket(rawURL string, timeout time.Duration, header http.Header) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream URL %s: %v", rawURL, err)
+	}
+
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "wss":
+		if u.Port() == "" {
+			host += ":443"
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	case "ws":
+		if u.Port() == "" {
+			host += ":80"
+		}
+		conn, err = dialer.Dial("tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported stream URL scheme %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %v", rawURL, err)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	path := u.RequestURI()
+	handshake := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	for name, values := range header {
+		for _, value := range values {
+			handshake += name + ": " + value + "\r\n"
+		}
+	}
+	handshake += "\r\n"
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, handshake); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error sending handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, &http.Request{Method: "GET"})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error reading handshake: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("stream handshake failed with status %s", res.Status)
+	}
+
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	if res.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, fmt.Errorf("stream handshake returned an invalid accept key")
+	}
+	conn.SetDeadline(time.Time{})
+
+	serverTime, _ := http.ParseTime(res.Header.Get("Date"))
+	return &wsConn{conn: conn, reader: reader, serverTime: serverTime}, nil
+}
+
+// streamURL turns an https base URL into the wss URL of path
+func streamURL(base string, path string) string {
+	base = strings.Replace(base, "https://", "wss://", 1)
+	base = strings.Replace(base, "http://", "ws://", 1)
+	return base + path
+}
+
+// writeFrame sends one masked, unfragmented frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	header = append(header, mask...)
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(append(header, masked...)); err != nil {
+		return fmt.Errorf("error writing to stream: %v", err)
+	}
+	return nil
+}
+
+// WriteMessage sends a text message
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.writeFrame(wsText, data)
+}
+
+// WriteBinary sends a binary message
+func (c *wsConn) WriteBinary(data []byte) error {
+	return c.writeFrame(wsBinary, data)
+}
+
+// ReadMessage returns the next complete text or binary message, answering pings on the way
+func (c *wsConn) ReadMessage() (int, []byte, error) {
+	var message []byte
+	messageType := 0
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			if len(payload) >= 2 {
+				return 0, nil, fmt.Errorf("stream closed by server (%d): %s", binary.BigEndian.Uint16(payload), string(payload[2:]))
+			}
+			return 0, nil, fmt.Errorf("stream closed by server")
+		case wsText, wsBinary:
+			messageType = int(opcode)
+			message = payload
+		case wsContinuation:
+			message = append(message, payload...)
+		default:
+			return 0, nil, fmt.Errorf("unknown stream opcode %d", opcode)
+		}
+
+		if len(message) > wsMaxMessage {
+			return 0, nil, fmt.Errorf("stream message exceeds %d bytes", wsMaxMessage)
+		}
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return false, 0, nil, fmt.Errorf("error reading from stream: %v", err)
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > wsMaxMessage {
+		return false, 0, nil, fmt.Errorf("stream frame exceeds %d bytes", wsMaxMessage)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(c.reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, fmt.Errorf("error reading from stream: %v", err)
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// SetReadDeadline bounds the wait for the next frame
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *wsConn) Close() error {
+	c.writeFrame(wsClose, []byte{0x03, 0xE8})
+	return c.conn.Close()
+}
+
+// backoff returns the wait before reconnect attempt n, doubling from 1 second up to 30 seconds
+func backoff(n int) time.Duration {
+	wait := time.Second
+	for i := 0; i < n && wait < 30*time.Second; i++ {
+		wait *= 2
+	}
+	if wait > 30*time.Second {
+		wait = 30 * time.Second
+	}
+	return wait
+}
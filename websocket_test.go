@@ -0,0 +1,228 @@
+package alpacaApiClient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsPeer is the server side of a test WebSocket connection
+type wsPeer struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// wsServer starts a server upgrading every request with wsHandler
+func wsServer(t *testing.T, date time.Time, handle func(*wsPeer)) *httptest.Server {
+	return httptest.NewServer(wsHandler(t, date, handle))
+}
+
+// wsHandler upgrades a request to a WebSocket and hands it to handle. The handshake response
+// carries date as its Date header unless it is zero.
+func wsHandler(t *testing.T, date time.Time, handle func(*wsPeer)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+		if !date.IsZero() {
+			response += "Date: " + date.UTC().Format(http.TimeFormat) + "\r\n"
+		}
+		if _, err := io.WriteString(conn, response+"\r\n"); err != nil {
+			t.Error(err)
+			return
+		}
+		handle(&wsPeer{t: t, conn: conn, reader: rw.Reader})
+	}
+}
+
+func wsStreamURL(srv *httptest.Server) string {
+	return streamURL(srv.URL, "/stream")
+}
+
+// write sends an unmasked frame like a server does
+func (p *wsPeer) write(fin bool, opcode byte, payload []byte) {
+	header := []byte{opcode}
+	if fin {
+		header[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := p.conn.Write(append(header, payload...)); err != nil {
+		p.t.Errorf("error writing frame: %v", err)
+	}
+}
+
+func (p *wsPeer) writeText(text string) {
+	p.write(true, wsText, []byte(text))
+}
+
+// read returns the next frame of the client, which must be masked
+func (p *wsPeer) read() (byte, []byte) {
+	p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(p.reader, header); err != nil {
+		p.t.Errorf("error reading frame: %v", err)
+		return 0, nil
+	}
+	if header[0]&0x80 == 0 {
+		p.t.Error("client sent a fragmented frame")
+	}
+	if header[1]&0x80 == 0 {
+		p.t.Error("client frame is not masked")
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(p.reader, ext)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(p.reader, ext)
+		length = binary.BigEndian.Uint64(ext)
+	}
+	mask := make([]byte, 4)
+	payload := make([]byte, length)
+	io.ReadFull(p.reader, mask)
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		p.t.Errorf("error reading frame payload: %v", err)
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestWebSocketFraming(t *testing.T) {
+	date := time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC)
+	sizes := []int{0, 125, 126, 0xFFFF, 0x10000}
+	done := make(chan struct{})
+
+	srv := wsServer(t, date, func(p *wsPeer) {
+		defer close(done)
+
+		// Client messages of every length encoding arrive masked and intact
+		for _, n := range sizes {
+			opcode, payload := p.read()
+			if opcode != wsText || !bytes.Equal(payload, bytes.Repeat([]byte("x"), n)) {
+				t.Errorf("received opcode %d with %d bytes, want a text message of %d", opcode, len(payload), n)
+			}
+		}
+		opcode, payload := p.read()
+		if opcode != wsBinary || string(payload) != "\x01\x02" {
+			t.Errorf("received opcode %d %q, want the binary message", opcode, payload)
+		}
+
+		// Echo every length encoding back
+		for _, n := range sizes {
+			p.write(true, wsText, bytes.Repeat([]byte("y"), n))
+		}
+
+		// A fragmented message with a ping and a pong between its fragments
+		p.write(false, wsText, []byte("frag"))
+		p.write(false, wsContinuation, []byte("men"))
+		p.write(true, wsPing, []byte("are you there"))
+		p.write(true, wsPong, nil)
+		p.write(true, wsContinuation, []byte("ted"))
+		if opcode, payload := p.read(); opcode != wsPong || string(payload) != "are you there" {
+			t.Errorf("received opcode %d %q, want a pong echoing the ping", opcode, payload)
+		}
+
+		// Close with a status code, the client answers with a close frame
+		p.write(true, wsClose, append([]byte{0x03, 0xE9}, "going away"...))
+		if opcode, payload := p.read(); opcode != wsClose || !bytes.Equal(payload, append([]byte{0x03, 0xE9}, "going away"...)) {
+			t.Errorf("received opcode %d %q, want the close frame echoed", opcode, payload)
+		}
+	})
+	defer srv.Close()
+
+	conn, err := dialWebSocket(wsStreamURL(srv), 5*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.conn.Close()
+	if !conn.serverTime.Equal(date) {
+		t.Errorf("server time %v, want the Date header %v", conn.serverTime, date)
+	}
+
+	for _, n := range sizes {
+		if err := conn.WriteMessage(bytes.Repeat([]byte("x"), n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.WriteBinary([]byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range sizes {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != wsText || len(message) != n {
+			t.Errorf("read type %d with %d bytes, want text of %d", messageType, len(message), n)
+		}
+	}
+
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != wsText || string(message) != "fragmented" {
+		t.Errorf("read type %d %q, want the reassembled text", messageType, message)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if err == nil || !strings.Contains(err.Error(), "1001") || !strings.Contains(err.Error(), "going away") {
+		t.Errorf("expected a close error with status 1001, got %v", err)
+	}
+	<-done
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Upgrade", "websocket")
+		w.Header().Set("Connection", "Upgrade")
+		w.Header().Set("Sec-WebSocket-Accept", "bogus")
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}))
+	defer srv.Close()
+
+	if _, err := dialWebSocket(wsStreamURL(srv), 5*time.Second, nil); err == nil || !strings.Contains(err.Error(), "accept key") {
+		t.Errorf("expected an invalid accept key error, got %v", err)
+	}
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	if _, err := dialWebSocket(wsStreamURL(plain), 5*time.Second, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a failed handshake, got %v", err)
+	}
+}